@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/AOzmond/usb-tree/lib"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -9,33 +12,53 @@ import (
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx        context.Context
+	source     lib.Source
+	configLogs []lib.Log // errors reading the config file, shown above the log of the source
 }
 
-// NewApp creates a new App application struct
-func NewApp() *App {
-	return &App{}
+// NewApp creates a new App application struct showing the devices of source
+func NewApp(source lib.Source) *App {
+	return &App{source: source}
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	path := lib.DefaultConfigPath()
+	cfg, err := lib.LoadConfig(path)
+	if err != nil {
+		runtime.LogErrorf(ctx, "reading config: %v", err)
+		a.configLogs = append(a.configLogs, lib.Log{
+			Time:  time.Now(),
+			Text:  fmt.Sprintf("Error reading config %s: %v", path, err),
+			State: lib.StateError,
+		})
+		return
+	}
+	lib.SetConfig(cfg)
+	lib.SetPolling(cfg.Polling)
 }
 
-// InitFrontend initializes the usb tree library with the app.updateCallback
+// InitFrontend starts the device source with the app.updateCallback
 func (a *App) InitFrontend() {
-	lib.Init(a.updateCallback)
+	if err := a.source.Start(a.updateCallback); err != nil {
+		runtime.LogErrorf(a.ctx, "reading USB devices: %v", err)
+	}
+	runtime.EventsEmit(a.ctx, "logsUpdated", a.logs())
+	runtime.EventsEmit(a.ctx, "healthUpdated", a.source.Health())
 }
 
-// Refresh relays refresh request to library, sets updated device tree on frontend
+// Refresh relays refresh request to the device source, sets updated device tree on frontend
 func (a *App) Refresh() {
-	lib.Refresh()
+	a.source.Refresh()
 }
 
 // Exit will stop polling of new devices.
 func (a *App) Exit(ctx context.Context) {
-	lib.Stop()
+	a.source.Stop()
 }
 
 // updateCallback will emit update events on device changes.
@@ -45,6 +68,11 @@ func (a *App) updateCallback(newDevices []lib.Device) {
 		runtime.EventsEmit(a.ctx, "treeUpdated", tree)
 	}
 
-	logs := lib.GetLog()
-	runtime.EventsEmit(a.ctx, "logsUpdated", logs)
+	runtime.EventsEmit(a.ctx, "logsUpdated", a.logs())
+	runtime.EventsEmit(a.ctx, "healthUpdated", a.source.Health())
+}
+
+// logs returns the log of the source, after the errors reading the config file.
+func (a *App) logs() []lib.Log {
+	return append(slices.Clone(a.configLogs), a.source.Log()...)
 }
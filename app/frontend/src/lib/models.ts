@@ -1,4 +1,40 @@
 // Models for go types.
+export class Interface {
+  number: number
+  class: string
+  subClass: number
+  protocol: number
+  driver: string
+
+  static createFrom(source: any = {}) {
+    return new Interface(source)
+  }
+
+  constructor(source: any = {}) {
+    if ("string" === typeof source) source = JSON.parse(source)
+    this.number = source["number"]
+    this.class = source["class"]
+    this.subClass = source["subClass"]
+    this.protocol = source["protocol"]
+    this.driver = source["driver"]
+  }
+}
+
+export class Violation {
+  kind: string
+  message: string
+
+  static createFrom(source: any = {}) {
+    return new Violation(source)
+  }
+
+  constructor(source: any = {}) {
+    if ("string" === typeof source) source = JSON.parse(source)
+    this.kind = source["kind"]
+    this.message = source["message"]
+  }
+}
+
 export class Device {
   path: number[]
   name: string
@@ -8,6 +44,27 @@ export class Device {
   bus: number
   devNum: number
   state: string
+  serial: string
+  alias: string
+  note: string
+  color: string
+  portLabel: string
+  class: string
+  devNode: string
+  driver: string
+  ports: number
+  controller: string
+  vendorName: string
+  productName: string
+  firmware: string
+  usbVersion: string
+  maxPower: number
+  selfPowered: boolean
+  powerState: string
+  autosuspend: boolean
+  interfaces: Interface[]
+  violations: Violation[]
+  host?: string
 
   static createFrom(source: any = {}) {
     return new Device(source)
@@ -23,6 +80,27 @@ export class Device {
     this.bus = source["bus"]
     this.devNum = source["devNum"]
     this.state = source["state"]
+    this.serial = source["serial"]
+    this.alias = source["alias"]
+    this.note = source["note"]
+    this.color = source["color"]
+    this.portLabel = source["portLabel"]
+    this.class = source["class"]
+    this.devNode = source["devNode"]
+    this.driver = source["driver"]
+    this.ports = source["ports"]
+    this.controller = source["controller"]
+    this.vendorName = source["vendorName"]
+    this.productName = source["productName"]
+    this.firmware = source["firmware"]
+    this.usbVersion = source["usbVersion"]
+    this.maxPower = source["maxPower"]
+    this.selfPowered = source["selfPowered"]
+    this.powerState = source["powerState"]
+    this.autosuspend = source["autosuspend"]
+    this.interfaces = source["interfaces"]
+    this.violations = source["violations"]
+    this.host = source["host"]
   }
 }
 
@@ -65,6 +143,9 @@ export class Log {
   Text: string
   State: string
   Speed: string
+  PortLabel: string
+  Host?: string
+  Seq?: number
 
   static createFrom(source: any = {}) {
     return new Log(source)
@@ -76,6 +157,9 @@ export class Log {
     this.Text = source["Text"]
     this.State = source["State"]
     this.Speed = source["Speed"]
+    this.PortLabel = source["PortLabel"]
+    this.Host = source["Host"]
+    this.Seq = source["Seq"]
   }
 
   convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -96,3 +180,26 @@ export class Log {
     return a
   }
 }
+
+export class HealthStatus {
+  state: string
+  error?: string
+  failures?: number
+  // Go type: time
+  lastPoll?: Date
+  // Go type: time
+  nextRetry?: Date
+
+  static createFrom(source: any = {}) {
+    return new HealthStatus(source)
+  }
+
+  constructor(source: any = {}) {
+    if ("string" === typeof source) source = JSON.parse(source)
+    this.state = source["state"]
+    this.error = source["error"]
+    this.failures = source["failures"]
+    this.lastPoll = source["lastPoll"]
+    this.nextRetry = source["nextRetry"]
+  }
+}
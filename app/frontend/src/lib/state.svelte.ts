@@ -1,11 +1,12 @@
 import { writable } from "svelte/store"
 
-import { Log, TreeNode } from "$lib/models"
+import { HealthStatus, Log, TreeNode } from "$lib/models"
 import { EventsOn } from "$wailsjs/runtime/runtime.js"
 import { InitFrontend, Refresh } from "$wailsjs/go/main/App"
 
 export const deviceTree = writable<TreeNode[]>([])
 export const deviceLogs = writable<Log[]>([])
+export const health = writable<HealthStatus>(new HealthStatus({ state: "starting" }))
 
 export type CarbonTheme = "g100" | "white"
 
@@ -29,6 +30,9 @@ export function Init(): void {
   EventsOn("logsUpdated", (logs: Log[]) => {
     deviceLogs.set(logs)
   })
+  EventsOn("healthUpdated", (status: HealthStatus) => {
+    health.set(status)
+  })
   InitFrontend().then()
 }
 
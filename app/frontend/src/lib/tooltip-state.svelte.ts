@@ -6,6 +6,11 @@ export type TooltipContent = {
   devNum: number | null
   vendorId: string | null
   productId: string | null
+  name: string | null
+  alias: string | null
+  note: string | null
+  portLabel: string | null
+  violations: string[] | null
 }
 
 export type TooltipPlacement = "top" | "bottom"
@@ -1,9 +1,10 @@
-import { Plus, Minus, Dot } from "@lucide/svelte"
+import { Plus, Minus, Dot, ShieldAlert } from "@lucide/svelte"
 
 export const iconByState = {
   added: Plus,
   removed: Minus,
   normal: Dot,
+  violation: ShieldAlert,
 } as const
 
 export function formatTimestamp(time: Date) {
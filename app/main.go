@@ -2,6 +2,11 @@ package main
 
 import (
 	"embed"
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,8 +17,28 @@ import (
 var assets embed.FS
 
 func main() {
+	flags := flag.NewFlagSet("usb-tree", flag.ExitOnError)
+	connect := flags.String("connect", "", `show the devices of a "usb-tree serve" daemon at this address`)
+	debugLog := flags.String("debug-log", "", "append a debug log of polls, cache misses and errors to this file")
+	_ = flags.Parse(os.Args[1:])
+
+	if *debugLog != "" {
+		file, err := os.OpenFile(*debugLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		defer file.Close()
+		lib.SetLogger(slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
+	source := lib.Local
+	if *connect != "" {
+		source = lib.Connect(*connect)
+	}
+
 	// Create an instance of the app structure
-	app := NewApp()
+	app := NewApp(source)
 
 	// Create application with options
 	err := wails.Run(&options.App{
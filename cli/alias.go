@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+var aliasEditorKeys = struct {
+	Save   key.Binding
+	Cancel key.Binding
+}{
+	Save:   key.NewBinding(key.WithKeys("enter")),
+	Cancel: key.NewBinding(key.WithKeys("esc")),
+}
+
+// newAliasInput creates the text input used to edit the alias of the selected device.
+func newAliasInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "Alias: "
+	input.Placeholder = "empty to clear"
+	input.CharLimit = 64
+	return input
+}
+
+// startAliasEditor opens the alias editor prefilled with the selected device's alias.
+func (m *Model) startAliasEditor() tea.Cmd {
+	m.editingAlias = true
+	m.aliasError = ""
+	m.aliasInput.SetValue(m.selectedDevice.Alias)
+	m.aliasInput.CursorEnd()
+	m.aliasInput.SetWidth(max(0, m.windowWidth-borderSpacing-(2*horizontalPadding)-len(m.aliasInput.Prompt)-1))
+	return m.aliasInput.Focus()
+}
+
+// updateAliasEditor routes key presses to the alias editor while it is open.
+func (m Model) updateAliasEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, aliasEditorKeys.Cancel):
+		m.closeAliasEditor()
+		return m, nil
+
+	case key.Matches(msg, aliasEditorKeys.Save):
+		if err := m.saveAlias(m.aliasInput.Value()); err != nil {
+			m.aliasError = err.Error()
+			return m, nil
+		}
+		m.closeAliasEditor()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.aliasInput, cmd = m.aliasInput.Update(msg)
+	return m, cmd
+}
+
+// closeAliasEditor hides the alias editor and discards its contents.
+func (m *Model) closeAliasEditor() {
+	m.editingAlias = false
+	m.aliasError = ""
+	m.aliasInput.Blur()
+	m.aliasInput.Reset()
+}
+
+// saveAlias stores the alias for the selected device in the config file and applies it on the next poll.
+func (m *Model) saveAlias(alias string) error {
+	if m.selectedDevice == nil {
+		return nil
+	}
+
+	cfg := lib.GetConfig()
+	cfg.SetAlias(*m.selectedDevice, alias)
+	if m.configPath != "" {
+		if err := lib.SaveConfig(m.configPath, cfg); err != nil {
+			return err
+		}
+	}
+	lib.SetConfig(cfg)
+	return nil
+}
+
+// aliasEditorView renders the alias editor in place of the tooltip.
+func (m *Model) aliasEditorView() string {
+	nameStyle := windowStyle.Foreground(nameTextColor)
+	content := nameStyle.Render(m.selectedDevice.Name+" ("+lib.MatcherFor(*m.selectedDevice).String()+")") +
+		"\n" + m.aliasInput.View()
+	if m.aliasError != "" {
+		content += "\n" + windowStyle.Foreground(removedStateColor).Render(m.aliasError)
+	}
+	return content
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// checkBaseline compares the devices with the baseline, if any, and records the deviations to mark.
+func (m *Model) checkBaseline(devices []lib.Device) {
+	if m.baseline == nil || devices == nil {
+		return
+	}
+
+	report := m.baseline.Check(devices)
+	m.baselineDeviations = report.DeviationsByKey()
+	m.baselineMissing = 0
+	for _, deviation := range report.Deviations() {
+		if deviation.Kind == lib.DeviationMissing {
+			m.baselineMissing++
+		}
+	}
+}
+
+// baselineStatus summarises the baseline check for the status line.
+func (m *Model) baselineStatus() string {
+	if m.baseline == nil {
+		return ""
+	}
+
+	var problems []string
+	if m.baselineMissing > 0 {
+		problems = append(problems, strconv.Itoa(m.baselineMissing)+" missing")
+	}
+	if len(m.baselineDeviations) > 0 {
+		problems = append(problems, strconv.Itoa(len(m.baselineDeviations))+" deviating")
+	}
+	if len(problems) == 0 {
+		return baselineOKStyle.Render(" Baseline: OK")
+	}
+
+	return baselineDeviationStyle.Render(" Baseline: " + strings.Join(problems, ", "))
+}
+
+// deviationMessages returns the baseline deviations of a device, joined for display.
+func (m *Model) deviationMessages(device *lib.Device) string {
+	var messages []string
+	for _, deviation := range m.baselineDeviations[device.Key()] {
+		messages = append(messages, deviation.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
@@ -5,6 +5,7 @@ import (
 
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -13,12 +14,21 @@ import (
 
 type focusIndex int
 
+// Options configures the Model created by InitialModel.
+type Options struct {
+	ConfigPath string        // file the alias editor saves to
+	Baseline   *lib.Baseline // expected devices whose deviations are marked in the tree, if set
+	Source     lib.Source    // where the devices come from, lib.Local when nil
+}
+
 // Model represents the primary structure containing application state and views.
 type Model struct {
 	windowWidth         int
 	windowHeight        int
 	statusHeight        int
 	statusLine          string
+	source              lib.Source
+	hosts               *lib.MultiSource // set when showing the devices of several daemons
 	updateChan          chan []lib.Device
 	roots               []*lib.TreeNode
 	collapsed           map[string]bool // tracks which nodes are collapsed by their unique key
@@ -30,10 +40,21 @@ type Model struct {
 	log                 []lib.Log
 	logContent          string
 	helpModel           help.Model
+	keys                keyMap // the key bindings, with those the source does not support disabled
 	focusedView         focusIndex
 	lastUpdated         time.Time
 	logHasNew           bool
 	instructionsVisible bool
+	configPath          string
+	aliasInput          textinput.Model
+	editingAlias        bool
+	aliasError          string
+	baseline            *lib.Baseline
+	baselineDeviations  map[string][]lib.Deviation // deviations of connected devices by device key
+	baselineMissing     int
+	searchInput         textinput.Model
+	searching           bool
+	searchError         string
 }
 
 const (
@@ -49,8 +70,12 @@ const (
 )
 
 // InitialModel initializes and returns a new Model instance with values for state and views.
-func InitialModel() Model {
+func InitialModel(options Options) Model {
 	updates := make(chan []lib.Device, 1)
+	source := options.Source
+	if source == nil {
+		source = lib.Local
+	}
 
 	helpModel := help.New()
 	helpModel.Styles.ShortDesc = windowStyle
@@ -59,20 +84,33 @@ func InitialModel() Model {
 	helpModel.Styles.FullKey = windowStyle
 	helpModel.Styles.FullDesc = windowStyle
 
+	hosts, _ := source.(*lib.MultiSource)
+	modelKeys := keys
+	// Aliases are saved to the local config file, which does not apply to the devices of a daemon.
+	modelKeys.EditAlias.SetEnabled(source == lib.Local)
+
 	m := Model{
 		helpModel:   helpModel,
+		keys:        modelKeys,
 		focusedView: treeView,
 		lastUpdated: time.Now(),
 		treeCursor:  0,
+		source:      source,
+		searchInput: newSearchInput(),
 		updateChan:  updates,
 		collapsed:   make(map[string]bool),
+		configPath:  options.ConfigPath,
+		aliasInput:  newAliasInput(),
+		baseline:    options.Baseline,
+		hosts:       hosts,
 	}
 	return m
 }
 
 // Init initializes the Model, preparing it to handle updateChan and rendering. It returns an optional initial command.
 func (m Model) Init() tea.Cmd {
-	lib.Init(func(devices []lib.Device) {
+	// A failed start is shown by the status line, from the Health of the source, while it keeps trying.
+	_ = m.source.Start(func(devices []lib.Device) {
 		m.updateChan <- devices
 	})
 	return waitForUpdate(m.updateChan)
@@ -117,9 +155,16 @@ func (m Model) View() tea.View {
 			BorderBottomForeground(edgeHighlightChangeColor)
 	}
 
+	tooltipContent := m.getSelectedDeviceInfo()
+	if m.editingAlias && m.selectedDevice != nil {
+		tooltipContent = m.aliasEditorView()
+	}
+	if m.searching {
+		tooltipContent = m.searchView()
+	}
 	tooltip := tooltipStyle.
 		Width(m.windowWidth).
-		Render(m.getSelectedDeviceInfo())
+		Render(tooltipContent)
 
 	appContent := lipgloss.JoinVertical(
 		lipgloss.Center,
@@ -150,7 +195,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.selectedDevice != nil {
 			previousKey = m.selectedDevice.Key()
 		}
-		m.roots = lib.BuildDeviceTree(devices)
+		m.roots = m.buildTree(devices)
+		m.checkBaseline(devices)
 		m.updateNodeCount()
 		if previousKey != "" {
 			if cursor, found := m.visibleNodeIndexByKey(previousKey); found {
@@ -165,7 +211,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.refreshContent()
 		m.scrollToCursor()
 
-		m.log = lib.GetLog()
+		m.log = m.source.Log()
 		m.logContent = m.formatLogContent()
 		m.logViewport.SetContent(m.logContent)
 		if wasAtBottom {
@@ -192,10 +238,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
-		if key.Matches(msg, keys.Quit) {
+		if m.editingAlias {
+			return m.updateAliasEditor(msg)
+		}
+		if m.searching {
+			return m.updateSearch(msg)
+		}
+		if key.Matches(msg, m.keys.Quit) {
 			return m, tea.Quit
 		}
-		if key.Matches(msg, keys.Instructions) {
+		if key.Matches(msg, m.keys.Instructions) {
 			m.instructionsVisible = !m.instructionsVisible
 			return m, nil
 		}
@@ -204,7 +256,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		switch {
-		case key.Matches(msg, keys.SwitchFocus):
+		case key.Matches(msg, m.keys.SwitchFocus):
 			if m.focusedView == treeView {
 				m.focusedView = logView
 			} else {
@@ -213,7 +265,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.refreshContent()
 			return m, nil
 
-		case key.Matches(msg, keys.Up):
+		case key.Matches(msg, m.keys.Up):
 			if m.focusedView == logView {
 				m.scrollLogUp()
 			} else if m.treeCursor > 0 {
@@ -225,7 +277,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.Down):
+		case key.Matches(msg, m.keys.Down):
 			if m.focusedView == logView {
 				m.scrollLogDown()
 			} else if m.treeCursor < (m.nodeCount - 1) {
@@ -237,14 +289,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.PageUp):
+		case key.Matches(msg, m.keys.PageUp):
 			if m.focusedView == logView {
 				m.logViewport.PageUp()
 				m.clampLogViewport()
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.PageDown):
+		case key.Matches(msg, m.keys.PageDown):
 			if m.focusedView == logView {
 				m.logViewport.PageDown()
 				m.updateLogScrollState()
@@ -252,7 +304,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.Collapse):
+		case key.Matches(msg, m.keys.Collapse):
 			if m.focusedView == treeView {
 				if node := m.getNodeAtCursor(); node != nil && len(node.Children) > 0 {
 					m.collapsed[node.Key()] = true
@@ -267,7 +319,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.Expand):
+		case key.Matches(msg, m.keys.Expand):
 			if m.focusedView == treeView {
 				if node := m.getNodeAtCursor(); node != nil && len(node.Children) > 0 {
 					delete(m.collapsed, node.Key())
@@ -280,9 +332,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
-		case key.Matches(msg, keys.Refresh):
-			lastUpdate, _ := lib.Refresh()
-			m.lastUpdated = lastUpdate
+		case key.Matches(msg, m.keys.Refresh):
+			m.lastUpdated = m.source.Refresh()
+
+		case key.Matches(msg, m.keys.EditAlias):
+			if m.focusedView == treeView && m.selectedDevice != nil && !m.selectedDevice.IsHost() {
+				return m, m.startAliasEditor()
+			}
+			return m, nil
+
+		case key.Matches(msg, m.keys.Search):
+			return m, m.startSearch()
 		}
 	}
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runAudit lists the connected devices breaking the allow list or looking like suspicious composites.
+func runAudit(args []string) int {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree audit [flags]")
+		fmt.Fprintln(flags.Output(), "Exits with 0 when no device is flagged, 1 when some are and 2 on errors.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file holding the allow list")
+	format := flags.String("format", "text", "output format: text or json")
+	_ = flags.Parse(args)
+
+	write, ok := auditWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return checkError
+	}
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	devices, err := listDevices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	flagged := lib.Flagged(devices)
+	if err := write(os.Stdout, flagged); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	if len(flagged) > 0 {
+		return checkDeviation
+	}
+
+	return checkOK
+}
+
+var auditWriters = map[string]func(io.Writer, []lib.Device) error{
+	"text": writeAuditText,
+	"json": writeAuditJSON,
+}
+
+func writeAuditText(w io.Writer, flagged []lib.Device) error {
+	for _, device := range flagged {
+		for _, violation := range device.Violations {
+			_, err := fmt.Fprintf(w, "%-8s %s:%s %s: %s\n", device.PortPath(), device.VendorID, device.ProductID,
+				device.DisplayName(), violation.Message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d flagged devices\n", len(flagged))
+
+	return err
+}
+
+func writeAuditJSON(w io.Writer, flagged []lib.Device) error {
+	return writeJSON(w, nonNil(flagged))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// Exit codes of the check and audit commands.
+const (
+	checkOK        = 0
+	checkDeviation = 1
+	checkError     = 2
+)
+
+// runCheck compares the connected devices with a baseline file.
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree check [flags] baseline.yaml")
+		fmt.Fprintln(flags.Output(), "Exits with 0 when the devices match the baseline, 1 on deviations and 2 on errors.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	format := flags.String("format", "text", "output format: text, json or junit")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return checkError
+	}
+
+	write, ok := checkWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return checkError
+	}
+
+	baseline, err := lib.LoadBaseline(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading baseline %s: %v\n", flags.Arg(0), err)
+		return checkError
+	}
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	devices, err := listDevices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	report := baseline.Check(devices)
+	if err := write(os.Stdout, report); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkError
+	}
+
+	if !report.OK() {
+		return checkDeviation
+	}
+
+	return checkOK
+}
+
+var checkWriters = map[string]func(io.Writer, lib.BaselineReport) error{
+	"text":  writeCheckText,
+	"json":  writeCheckJSON,
+	"junit": writeCheckJUnit,
+}
+
+func writeCheckText(w io.Writer, report lib.BaselineReport) error {
+	for _, result := range report.Results {
+		if len(result.Deviations) == 0 {
+			if _, err := fmt.Fprintf(w, "ok   %s on %s\n", result.Expected.Name, result.Device.PortPath()); err != nil {
+				return err
+			}
+		}
+	}
+	for _, deviation := range report.Deviations() {
+		if _, err := fmt.Fprintf(w, "FAIL %s\n", deviation.Message); err != nil {
+			return err
+		}
+	}
+
+	deviations := len(report.Deviations())
+	if deviations == 0 {
+		_, err := fmt.Fprintf(w, "%d devices match the baseline\n", len(report.Results))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d deviations from the baseline\n", deviations)
+
+	return err
+}
+
+func writeCheckJSON(w io.Writer, report lib.BaselineReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(struct {
+		OK bool `json:"ok"`
+		lib.BaselineReport
+	}{OK: report.OK(), BaselineReport: report})
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// writeCheckJUnit writes one test case per expected device and per unexpected device.
+func writeCheckJUnit(w io.Writer, report lib.BaselineReport) error {
+	suite := junitSuite{Name: "usb-tree baseline"}
+	for _, result := range report.Results {
+		testCase := junitCase{Name: result.Expected.Name, ClassName: "baseline.expected"}
+		for _, deviation := range result.Deviations {
+			testCase.Failures = append(testCase.Failures, junitFailure{Type: string(deviation.Kind), Message: deviation.Message})
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	for _, deviation := range report.Unexpected {
+		suite.Cases = append(suite.Cases, junitCase{
+			Name:      deviation.Device.PortPath() + " " + deviation.Device.VendorID + ":" + deviation.Device.ProductID,
+			ClassName: "baseline.unexpected",
+			Failures:  []junitFailure{{Type: string(deviation.Kind), Message: deviation.Message}},
+		})
+	}
+	suite.Tests = len(suite.Cases)
+	for _, testCase := range suite.Cases {
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// addDebugLogFlag adds the flag writing a debug log of the monitoring, to diagnose problems on a machine.
+func addDebugLogFlag(flags *flag.FlagSet) *string {
+	return flags.String("debug-log", "", "append a debug log of polls, cache misses and errors to this file")
+}
+
+// openDebugLog makes lib write its debug log to the file at path, if not empty, and returns a function
+// closing the file.
+func openDebugLog(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	lib.SetLogger(slog.New(slog.NewTextHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	return func() {
+		lib.SetLogger(slog.New(slog.DiscardHandler))
+		_ = file.Close()
+	}, nil
+}
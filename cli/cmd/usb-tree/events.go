@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runEvents monitors the devices without the UI and prints one line per event.
+func runEvents(args []string) int {
+	flags := flag.NewFlagSet("events", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree events [flags]")
+		fmt.Fprintln(flags.Output(), "Prints device events until interrupted, --count events were printed or --timeout passed.")
+		fmt.Fprintln(flags.Output(), "Exits with 1 when the timeout passes before --count events were printed and 2 on errors.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	asJSON := flags.Bool("json", false, "print one JSON object per line (NDJSON)")
+	filter := flags.String("filter", "", `only print events of devices matching a filter expression, e.g. "vid:046d class:hid speed<480"`)
+	types := flags.String("type", "", "comma-separated event types to print: added, removed, changed, violation")
+	count := flags.Int("count", 0, "exit after printing this many events")
+	timeout := flags.Duration("timeout", 0, "exit after this long")
+	sinks := addSinkFlags(flags)
+	polling := addPollingFlags(flags)
+	debugLog := addDebugLogFlag(flags)
+	_ = flags.Parse(args)
+
+	deviceFilter, err := lib.ParseFilter(*filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	var eventTypes []lib.EventType
+	for _, name := range strings.FieldsFunc(*types, func(r rune) bool { return r == ',' }) {
+		eventType := lib.EventType(strings.TrimSpace(name))
+		if !slices.Contains(lib.EventTypes(), eventType) {
+			fmt.Fprintf(os.Stderr, "Error: unknown event type %q, expected added, removed, changed or violation\n", eventType)
+			return 2
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := polling.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	closeDebugLog, err := openDebugLog(*debugLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeDebugLog()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	forwarder, err := sinks.open()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer forwarder.close()
+	events, unsubscribe := lib.Subscribe()
+	defer unsubscribe()
+	_, err = lib.Init(forwarder.update)
+	defer lib.Stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	write := func(event lib.Event) error { return writeEventText(os.Stdout, event) }
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		write = func(event lib.Event) error { return encoder.Encode(event) }
+	}
+
+	printed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			if *count > 0 && printed < *count && ctx.Err() == context.DeadlineExceeded {
+				return 1
+			}
+			return 0
+
+		case event := <-events:
+			if !deviceFilter.Matches(event.Device) {
+				continue
+			}
+			if len(eventTypes) > 0 && !slices.Contains(eventTypes, event.Type) {
+				continue
+			}
+			if err := write(event); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 2
+			}
+
+			printed++
+			if *count > 0 && printed >= *count {
+				return 0
+			}
+		}
+	}
+}
+
+// writeEventText prints an event as a line of human-readable text.
+func writeEventText(w io.Writer, event lib.Event) error {
+	device := event.Device
+	_, err := fmt.Fprintf(w, "%s %-9s %-8s %s:%s %s\n", event.Time.Format(time.TimeOnly), event.Type, device.PortPath(),
+		device.VendorID, device.ProductID, cli.WithPortLabel(device.DisplayName(), device.PortLabel))
+
+	return err
+}
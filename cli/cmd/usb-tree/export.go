@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runExport renders the device tree as a Graphviz DOT or Mermaid graph.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	options := addSourceFlags(flags)
+	format := flags.String("format", "dot", "graph format: dot or mermaid")
+	cluster := flags.String("cluster", "none", "group devices by none, bus or controller")
+	_ = flags.Parse(args)
+
+	write, ok := graphWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return 2
+	}
+	clusterOf, ok := clusterKeys[*cluster]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown clustering %q\n", *cluster)
+		return 2
+	}
+
+	snapshot, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if err := write(os.Stdout, newGraph(lib.BuildDeviceTree(snapshot.Devices), clusterOf)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// A graph is the device tree flattened into labeled nodes and edges, grouped into clusters.
+type graph struct {
+	clusters []graphCluster
+	edges    []graphEdge
+}
+
+type graphCluster struct {
+	label string // empty when the nodes are not clustered
+	nodes []graphNode
+}
+
+type graphNode struct {
+	id    string
+	lines []string
+}
+
+type graphEdge struct {
+	from, to string
+	port     int
+}
+
+// clusterKeys return the name of the cluster a root hub and its devices belong to.
+var clusterKeys = map[string]func(lib.Device) string{
+	"none": func(lib.Device) string { return "" },
+	"bus":  func(device lib.Device) string { return "Bus " + strconv.Itoa(device.Bus) },
+	"controller": func(device lib.Device) string {
+		if device.Controller == "" {
+			return "Bus " + strconv.Itoa(device.Bus)
+		}
+		return "Controller " + device.Controller
+	},
+}
+
+func newGraph(roots []*lib.TreeNode, clusterOf func(lib.Device) string) graph {
+	var g graph
+	clusterIndex := map[string]int{}
+
+	for _, root := range roots {
+		label := clusterOf(root.Device)
+		index, ok := clusterIndex[label]
+		if !ok {
+			index = len(g.clusters)
+			clusterIndex[label] = index
+			g.clusters = append(g.clusters, graphCluster{label: label})
+		}
+
+		root.Walk(func(node *lib.TreeNode, ancestors []*lib.TreeNode) bool {
+			device := node.Device
+			if len(ancestors) > 0 {
+				parent := ancestors[len(ancestors)-1]
+				g.edges = append(g.edges, graphEdge{from: parent.PortPath(), to: device.PortPath(), port: device.Path[len(device.Path)-1]})
+			}
+			lines := []string{strings.TrimSpace(device.DisplayName()), device.VendorID + ":" + device.ProductID}
+			if speed := strings.TrimSpace(cli.FormatSpeed(device.Speed)); speed != "" {
+				lines = append(lines, speed)
+			}
+			if device.PortLabel != "" {
+				lines = append(lines, "["+device.PortLabel+"]")
+			}
+			g.clusters[index].nodes = append(g.clusters[index].nodes, graphNode{id: device.PortPath(), lines: lines})
+			return true
+		})
+	}
+
+	return g
+}
+
+var graphWriters = map[string]func(io.Writer, graph) error{
+	"dot":     writeDot,
+	"mermaid": writeMermaid,
+}
+
+func writeDot(w io.Writer, g graph) error {
+	var sb strings.Builder
+	sb.WriteString("digraph usb {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	for i, cluster := range g.clusters {
+		indent := "\t"
+		if cluster.label != "" {
+			fmt.Fprintf(&sb, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, dotQuote(cluster.label))
+			indent = "\t\t"
+		}
+		for _, node := range cluster.nodes {
+			fmt.Fprintf(&sb, "%s%s [label=%s];\n", indent, dotQuote(node.id), dotQuote(strings.Join(node.lines, "\n")))
+		}
+		if cluster.label != "" {
+			sb.WriteString("\t}\n")
+		}
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&sb, "\t%s -> %s [label=%s];\n", dotQuote(edge.from), dotQuote(edge.to), dotQuote(strconv.Itoa(edge.port)))
+	}
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotQuote returns s as a DOT string literal.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+func writeMermaid(w io.Writer, g graph) error {
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	for i, cluster := range g.clusters {
+		indent := "  "
+		if cluster.label != "" {
+			fmt.Fprintf(&sb, "  subgraph cluster_%d [%s]\n", i, mermaidQuote(cluster.label))
+			indent = "    "
+		}
+		for _, node := range cluster.nodes {
+			fmt.Fprintf(&sb, "%s%s[%s]\n", indent, mermaidID(node.id), mermaidQuote(strings.Join(node.lines, "<br/>")))
+		}
+		if cluster.label != "" {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&sb, "  %s -->|%d| %s\n", mermaidID(edge.from), edge.port, mermaidID(edge.to))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// mermaidID turns a port path such as "1-3.2" into a Mermaid node ID such as "p1_3_2".
+func mermaidID(portPath string) string {
+	return "p" + strings.NewReplacer("-", "_", ".", "_").Replace(portPath)
+}
+
+// mermaidQuote returns s as a Mermaid string, with quotes replaced by their HTML entity.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
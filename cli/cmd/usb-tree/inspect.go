@@ -0,0 +1,120 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runInspect prints every known detail of the devices identified by a port path, a vid:pid pair or a serial.
+func runInspect(args []string) int {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree inspect [flags] <bus-path|vid:pid|serial>")
+		flags.PrintDefaults()
+	}
+	options := addQueryFlags(flags)
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	query := flags.Arg(0)
+
+	devices, matches, err := options.devices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var found []lib.Device
+	for _, device := range devices {
+		if identifies(query, device) && matches(device) {
+			found = append(found, device)
+		}
+	}
+	if len(found) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no device matches %q\n", query)
+		return 1
+	}
+
+	if options.json {
+		err = writeJSON(os.Stdout, found)
+	} else {
+		err = writeDeviceDetails(os.Stdout, found)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// identifies reports whether the query names the device by port path, vid:pid or serial number.
+func identifies(query string, device lib.Device) bool {
+	if query == device.PortPath() || (device.Serial != "" && query == device.Serial) {
+		return true
+	}
+
+	vid, pid, found := strings.Cut(query, ":")
+	return found && strings.EqualFold(vid, device.VendorID) && strings.EqualFold(pid, device.ProductID)
+}
+
+func writeDeviceDetails(w io.Writer, devices []lib.Device) error {
+	for i, device := range devices {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		row := func(label, value string) {
+			if value != "" {
+				fmt.Fprintf(table, "%s:\t%s\n", label, value)
+			}
+		}
+		row("Name", strings.TrimSpace(device.Name))
+		row("Alias", device.Alias)
+		row("Note", device.Note)
+		row("Port", device.PortPath())
+		row("Port label", device.PortLabel)
+		row("Bus", fmt.Sprintf("%03d", device.Bus))
+		row("Device", fmt.Sprintf("%03d", device.DevNum))
+		row("ID", device.VendorID+":"+device.ProductID)
+		row("Speed", strings.TrimSpace(cli.FormatSpeed(device.Speed)))
+		row("Class", device.Class)
+		row("Serial", device.Serial)
+		row("Firmware", device.Firmware)
+		row("USB version", device.USBVersion)
+		row("Device node", device.DevNode)
+		row("Driver", device.Driver)
+		if device.Autosuspend {
+			row("Power state", device.PowerState+" (autosuspend)")
+		} else {
+			row("Power state", device.PowerState)
+		}
+		if device.Ports > 0 {
+			row("Ports", strconv.Itoa(device.Ports))
+		}
+		for _, iface := range device.Interfaces {
+			row(fmt.Sprintf("Interface %d", iface.Number),
+				fmt.Sprintf("%s (subclass %d, protocol %d) %s", iface.Class, iface.SubClass, iface.Protocol, iface.Driver))
+		}
+		for _, violation := range device.Violations {
+			row("Policy violation", violation.Message)
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
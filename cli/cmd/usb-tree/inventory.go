@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// inventoryRow is one physical device of the inventory with what the history knows about it.
+type inventoryRow struct {
+	hostname string
+	device   lib.Device
+	sighting lib.Sighting
+	seen     bool
+}
+
+// inventoryColumn is a column that can be selected with --columns.
+type inventoryColumn struct {
+	name  string
+	value func(inventoryRow) string
+}
+
+var inventoryColumns = []inventoryColumn{
+	{"hostname", func(r inventoryRow) string { return r.hostname }},
+	{"port", func(r inventoryRow) string { return r.device.PortPath() }},
+	{"vid", func(r inventoryRow) string { return r.device.VendorID }},
+	{"pid", func(r inventoryRow) string { return r.device.ProductID }},
+	{"vendor", func(r inventoryRow) string { return r.device.VendorName }},
+	{"product", func(r inventoryRow) string { return r.device.ProductName }},
+	{"serial", func(r inventoryRow) string { return r.device.Serial }},
+	{"class", func(r inventoryRow) string { return r.device.Class }},
+	{"firmware", func(r inventoryRow) string { return r.device.Firmware }},
+	{"driver", func(r inventoryRow) string { return r.device.Driver }},
+	{"first_seen", func(r inventoryRow) string { return r.seenTime(r.sighting.FirstSeen) }},
+	{"last_seen", func(r inventoryRow) string { return r.seenTime(r.sighting.LastSeen) }},
+}
+
+func (r inventoryRow) seenTime(t time.Time) string {
+	if !r.seen {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// runInventory prints one row per physical device, for collecting the peripherals of many machines.
+func runInventory(args []string) int {
+	flags := flag.NewFlagSet("inventory", flag.ExitOnError)
+	options := addSourceFlags(flags)
+	format := flags.String("format", "csv", "output format: csv or json")
+	columnList := flags.String("columns", "", "comma separated columns to print, default all: "+columnNames(inventoryColumns))
+	historyPath := flags.String("history", lib.DefaultHistoryPath(),
+		"file recording when devices were first and last seen, empty to disable")
+	_ = flags.Parse(args)
+
+	write, ok := inventoryWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return 2
+	}
+	columns, err := selectColumns(*columnList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	snapshot, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// The history only describes this machine, so it is neither used nor updated for snapshots.
+	var history lib.History
+	if *historyPath != "" && options.snapshot == "" {
+		if history, err = lib.LoadHistory(*historyPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading history %s: %v\n", *historyPath, err)
+			return 1
+		}
+		history.Record(snapshot.Devices, snapshot.Time)
+		if err := lib.SaveHistory(*historyPath, history); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: writing history %s: %v\n", *historyPath, err)
+			return 1
+		}
+	}
+
+	var rows []inventoryRow
+	for _, device := range snapshot.Devices {
+		// Root hubs are part of the host controller rather than attached peripherals.
+		if len(device.Path) == 0 {
+			continue
+		}
+		row := inventoryRow{hostname: snapshot.Hostname, device: device}
+		row.sighting, row.seen = history.Lookup(device)
+		rows = append(rows, row)
+	}
+
+	if err := write(os.Stdout, columns, rows); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// selectColumns returns the columns named in the comma separated list, or all of them for an empty list.
+func selectColumns(list string) ([]inventoryColumn, error) {
+	if list == "" {
+		return inventoryColumns, nil
+	}
+
+	var columns []inventoryColumn
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(inventoryColumns, func(c inventoryColumn) bool { return c.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q, expected one of %s", name, columnNames(inventoryColumns))
+		}
+		columns = append(columns, inventoryColumns[i])
+	}
+
+	return columns, nil
+}
+
+func columnNames(columns []inventoryColumn) string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	return strings.Join(names, ",")
+}
+
+var inventoryWriters = map[string]func(io.Writer, []inventoryColumn, []inventoryRow) error{
+	"csv":  writeInventoryCSV,
+	"json": writeInventoryJSON,
+}
+
+func writeInventoryCSV(w io.Writer, columns []inventoryColumn, rows []inventoryRow) error {
+	writer := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = column.name
+	}
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = column.value(row)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func writeInventoryJSON(w io.Writer, columns []inventoryColumn, rows []inventoryRow) error {
+	records := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		records[i] = map[string]string{}
+		for _, column := range columns {
+			records[i][column.name] = column.value(row)
+		}
+	}
+
+	return writeJSON(w, records)
+}
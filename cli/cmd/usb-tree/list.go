@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// queryOptions holds the flags shared by the commands printing devices.
+type queryOptions struct {
+	configPath string
+	json       bool
+	filter     string
+	snapshot   string
+}
+
+func addQueryFlags(flags *flag.FlagSet) *queryOptions {
+	options := addSourceFlags(flags)
+	flags.BoolVar(&options.json, "json", false, "print JSON")
+	flags.StringVar(&options.filter, "filter", "", `only show devices matching a filter expression, e.g. "vid:046d class:hid speed<480"`)
+	return options
+}
+
+// addSourceFlags registers the flags selecting where devices are read from.
+func addSourceFlags(flags *flag.FlagSet) *queryOptions {
+	options := &queryOptions{}
+	flags.StringVar(&options.configPath, "config", lib.DefaultConfigPath(), "path to the configuration file")
+	flags.StringVar(&options.snapshot, "snapshot", "", "read the devices from a snapshot file instead of this machine")
+	return options
+}
+
+// devices loads the configuration and returns the connected devices, or those of the snapshot, with a
+// function reporting whether a device passes the filter.
+func (o *queryOptions) devices() ([]lib.Device, func(lib.Device) bool, error) {
+	filter, err := lib.ParseFilter(o.filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	snapshot, err := o.load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return snapshot.Devices, filter.Matches, nil
+}
+
+// load returns the snapshot file, if one was given, or a snapshot of this machine.
+func (o *queryOptions) load() (lib.Snapshot, error) {
+	if o.snapshot != "" {
+		return lib.LoadSnapshot(o.snapshot)
+	}
+
+	if err := loadConfig(o.configPath); err != nil {
+		return lib.Snapshot{}, err
+	}
+	devices, err := listDevices()
+	if err != nil {
+		return lib.Snapshot{}, err
+	}
+
+	return lib.NewSnapshot(devices), nil
+}
+
+// runList prints the connected devices as a flat table.
+func runList(args []string) int {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	options := addQueryFlags(flags)
+	format := flags.String("format", "table", "output format: table or lsusb")
+	_ = flags.Parse(args)
+
+	if code := checkFormat(*format, options.json, "table", "lsusb"); code != 0 {
+		return code
+	}
+
+	devices, matches, err := options.devices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var listed []lib.Device
+	for _, device := range devices {
+		if matches(device) {
+			listed = append(listed, device)
+		}
+	}
+
+	switch {
+	case options.json:
+		err = writeJSON(os.Stdout, nonNil(listed))
+	case *format == "lsusb":
+		err = writeLsusb(os.Stdout, listed)
+	default:
+		err = writeDeviceTable(os.Stdout, listed)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// checkFormat reports a --format that is not one of formats, the first being the default, or one given with
+// --json, and returns the exit code of the usage error, or 0 when the format is valid.
+func checkFormat(format string, json bool, formats ...string) int {
+	if !slices.Contains(formats, format) {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q, expected %s\n", format, strings.Join(formats, " or "))
+		return 2
+	}
+	if json && format != formats[0] {
+		fmt.Fprintf(os.Stderr, "Error: --json cannot be combined with --format %s\n", format)
+		return 2
+	}
+
+	return 0
+}
+
+func writeDeviceTable(w io.Writer, devices []lib.Device) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PORT\tID\tSPEED\tCLASS\tSERIAL\tNAME")
+	for _, device := range devices {
+		fmt.Fprintf(table, "%s\t%s:%s\t%s\t%s\t%s\t%s\n", device.PortPath(), device.VendorID, device.ProductID,
+			strings.TrimSpace(cli.FormatSpeed(device.Speed)), device.Class, orDash(device.Serial), device.DisplayName())
+	}
+
+	return table.Flush()
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// nonNil turns a nil slice into an empty one so it is printed as [] rather than null.
+func nonNil[T any](values []T) []T {
+	if values == nil {
+		return []T{}
+	}
+
+	return values
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// lsusbClassNames holds the class names printed by lsusb -t for the short class names used by lib.
+var lsusbClassNames = map[string]string{
+	"per-interface":    ">ifc",
+	"audio":            "Audio",
+	"comm":             "Communications",
+	"hid":              "Human Interface Device",
+	"physical":         "Physical Interface Device",
+	"image":            "Imaging",
+	"printer":          "Printer",
+	"storage":          "Mass Storage",
+	"hub":              "Hub",
+	"cdc-data":         "CDC Data",
+	"smart-card":       "Chip/SmartCard",
+	"content-security": "Content Security",
+	"video":            "Video",
+	"healthcare":       "Personal Healthcare",
+	"audio-video":      "Audio/Video",
+	"billboard":        "Billboard",
+	"type-c-bridge":    "Type-C Bridge",
+	"diagnostic":       "Diagnostic",
+	"wireless":         "Wireless",
+	"misc":             "Miscellaneous Device",
+	"app-specific":     "Application Specific Interface",
+	"vendor-specific":  "Vendor Specific Class",
+}
+
+func lsusbClassName(class string) string {
+	if name, ok := lsusbClassNames[class]; ok {
+		return name
+	}
+
+	return class
+}
+
+// writeLsusb prints the devices like lsusb: "Bus 001 Device 002: ID 046d:c31c Logitech, Inc. Keyboard K120".
+func writeLsusb(w io.Writer, devices []lib.Device) error {
+	sorted := append([]lib.Device(nil), devices...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Bus != sorted[j].Bus {
+			return sorted[i].Bus < sorted[j].Bus
+		}
+		return sorted[i].DevNum > sorted[j].DevNum
+	})
+
+	for _, device := range sorted {
+		_, err := fmt.Fprintf(w, "Bus %03d Device %03d: ID %s:%s %s\n", device.Bus, device.DevNum,
+			device.VendorID, device.ProductID, strings.TrimSpace(device.Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeLsusbTree prints the device tree like lsusb -t, with one line per interface.
+func writeLsusbTree(w io.Writer, roots []*lib.TreeNode) error {
+	var err error
+	lib.Tree(roots).Walk(func(node *lib.TreeNode, ancestors []*lib.TreeNode) bool {
+		for _, line := range lsusbTreeLines(node.Device, len(ancestors)) {
+			if err == nil {
+				_, err = fmt.Fprintln(w, line)
+			}
+		}
+		return err == nil
+	})
+
+	return err
+}
+
+// lsusbTreeLines returns the lsusb -t lines of a device at the given depth below its root hub.
+func lsusbTreeLines(device lib.Device, depth int) []string {
+	speed := strings.TrimSpace(device.Speed) + "M"
+
+	if depth == 0 {
+		return []string{fmt.Sprintf("/:  Bus %02d.Port 1: Dev %d, Class=root_hub, Driver=%s, %s",
+			device.Bus, device.DevNum, withPorts(device.Driver, device.Ports), speed)}
+	}
+
+	indent := strings.Repeat(" ", 4*depth) + "|__ "
+	port := device.Path[len(device.Path)-1]
+	if len(device.Interfaces) == 0 {
+		return []string{fmt.Sprintf("%sPort %d: Dev %d, Class=%s, Driver=[none], %s",
+			indent, port, device.DevNum, lsusbClassName(device.Class), speed)}
+	}
+
+	lines := make([]string, 0, len(device.Interfaces))
+	for _, iface := range device.Interfaces {
+		driver := iface.Driver
+		if iface.Class == "hub" {
+			driver = withPorts(driver, device.Ports)
+		}
+		lines = append(lines, fmt.Sprintf("%sPort %d: Dev %d, If %d, Class=%s, Driver=%s, %s",
+			indent, port, device.DevNum, iface.Number, lsusbClassName(iface.Class), orNone(driver), speed))
+	}
+
+	return lines
+}
+
+// withPorts appends the port count of a hub to its driver name, e.g. "hub/4p".
+func withPorts(driver string, ports int) string {
+	driver = orNone(driver)
+	if ports == 0 {
+		return driver
+	}
+
+	return fmt.Sprintf("%s/%dp", driver, ports)
+}
+
+func orNone(driver string) string {
+	if driver == "" {
+		return "[none]"
+	}
+
+	return driver
+}
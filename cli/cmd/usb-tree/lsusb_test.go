@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AOzmond/usb-tree/lib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lsusbDevices = []lib.Device{
+	{
+		Path: []int{}, Name: "Linux Foundation 2.0 root hub", VendorID: "1d6b", ProductID: "0002", Speed: "480",
+		Bus: 1, DevNum: 1, Class: "hub", Driver: "ehci-pci", Ports: 4,
+	},
+	{
+		Path: []int{2}, Name: "Genesys Logic, Inc. Hub", VendorID: "05e3", ProductID: "0608", Speed: "480",
+		Bus: 1, DevNum: 2, Class: "hub", Ports: 4,
+		Interfaces: []lib.Interface{{Number: 0, Class: "hub", Driver: "hub"}},
+	},
+	{
+		Path: []int{2, 1}, Name: "Logitech, Inc. Keyboard K120 ", VendorID: "046d", ProductID: "c31c", Speed: "1.5",
+		Bus: 1, DevNum: 3, Class: "per-interface",
+		Interfaces: []lib.Interface{
+			{Number: 0, Class: "hid", Driver: "usbhid"},
+			{Number: 1, Class: "hid", Driver: "usbhid"},
+		},
+	},
+	{
+		Path: []int{2, 3}, Name: "SEGGER J-Link", VendorID: "1366", ProductID: "0105", Speed: "12",
+		Bus: 1, DevNum: 4, Class: "vendor-specific",
+	},
+	{
+		Path: []int{}, Name: "Linux Foundation 3.0 root hub", VendorID: "1d6b", ProductID: "0003", Speed: "5000",
+		Bus: 2, DevNum: 1, Class: "hub", Driver: "xhci_hcd", Ports: 2,
+	},
+	{
+		Path: []int{1}, Name: "SanDisk Corp. Ultra", VendorID: "0781", ProductID: "5581", Speed: "5000",
+		Bus: 2, DevNum: 2, Class: "per-interface",
+		Interfaces: []lib.Interface{{Number: 0, Class: "storage"}},
+	},
+}
+
+func TestWriteLsusb(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, writeLsusb(&out, lsusbDevices))
+
+	assert.Equal(t, `Bus 001 Device 004: ID 1366:0105 SEGGER J-Link
+Bus 001 Device 003: ID 046d:c31c Logitech, Inc. Keyboard K120
+Bus 001 Device 002: ID 05e3:0608 Genesys Logic, Inc. Hub
+Bus 001 Device 001: ID 1d6b:0002 Linux Foundation 2.0 root hub
+Bus 002 Device 002: ID 0781:5581 SanDisk Corp. Ultra
+Bus 002 Device 001: ID 1d6b:0003 Linux Foundation 3.0 root hub
+`, out.String())
+}
+
+func TestWriteLsusbTree(t *testing.T) {
+	var out strings.Builder
+	require.NoError(t, writeLsusbTree(&out, lib.BuildDeviceTree(lsusbDevices)))
+
+	assert.Equal(t, `/:  Bus 01.Port 1: Dev 1, Class=root_hub, Driver=ehci-pci/4p, 480M
+    |__ Port 2: Dev 2, If 0, Class=Hub, Driver=hub/4p, 480M
+        |__ Port 1: Dev 3, If 0, Class=Human Interface Device, Driver=usbhid, 1.5M
+        |__ Port 1: Dev 3, If 1, Class=Human Interface Device, Driver=usbhid, 1.5M
+        |__ Port 3: Dev 4, Class=Vendor Specific Class, Driver=[none], 12M
+/:  Bus 02.Port 1: Dev 1, Class=root_hub, Driver=xhci_hcd/2p, 5000M
+    |__ Port 1: Dev 2, If 0, Class=Mass Storage, Driver=[none], 5000M
+`, out.String())
+}
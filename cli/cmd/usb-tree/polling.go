@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// pollingFlags overrides the polling section of the configuration file for this run.
+type pollingFlags struct {
+	interval    time.Duration
+	settle      time.Duration
+	minInterval time.Duration
+}
+
+// addPollingFlags adds the flags setting how often the devices are polled and how changes are coalesced.
+func addPollingFlags(flags *flag.FlagSet) *pollingFlags {
+	polling := &pollingFlags{}
+	flags.DurationVar(&polling.interval, "poll-interval", 0,
+		"time between two polls of the devices, 250ms unless set here or in the config file")
+	flags.DurationVar(&polling.settle, "settle", 0,
+		"wait until the devices stopped changing for this long before updating, to coalesce bursts such as plugging in a hub")
+	flags.DurationVar(&polling.minInterval, "min-update-interval", 0, "least time between two updates")
+	return polling
+}
+
+// apply sets the timing of the polls: that of the configuration file loaded by loadConfig, overridden by the
+// flags that were set. The overrides are not saved to the file.
+func (p *pollingFlags) apply() error {
+	polling := lib.GetConfig().Polling
+	if p.interval != 0 {
+		polling.Interval = lib.Duration(p.interval)
+	}
+	if p.settle != 0 {
+		polling.Settle = lib.Duration(p.settle)
+	}
+	if p.minInterval != 0 {
+		polling.MinInterval = lib.Duration(p.minInterval)
+	}
+	if err := polling.Validate(); err != nil {
+		return err
+	}
+	lib.SetPolling(polling)
+
+	return nil
+}
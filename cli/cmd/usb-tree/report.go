@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+//go:embed templates/report.html.tmpl
+var reportHTML string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"speed": func(speed string) string { return strings.TrimSpace(cli.FormatSpeed(speed)) },
+}).Parse(reportHTML))
+
+// reportData is everything a report shows.
+type reportData struct {
+	Hostname string
+	Time     time.Time
+	Devices  []lib.Device
+	Roots    []*lib.TreeNode
+	Warnings []lib.Warning
+	Log      []lib.Log
+}
+
+// runReport writes a self-contained HTML or Markdown report of the device tree, its warnings and the event log.
+func runReport(args []string) int {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	options := addSourceFlags(flags)
+	format := flags.String("format", "html", "report format: html or markdown")
+	output := flags.String("output", "", "write the report to this file instead of standard output")
+	watch := flags.Duration("watch", 0, "record events for this long, or until interrupted, before writing the report")
+	logSize := flags.Int("log", 100, "number of recent log entries to include")
+	_ = flags.Parse(args)
+
+	write, ok := reportWriters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return 2
+	}
+	if *logSize < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid --log %d, expected a number of entries\n", *logSize)
+		return 2
+	}
+
+	var snapshot lib.Snapshot
+	var err error
+	if *watch > 0 && options.snapshot == "" {
+		snapshot, err = watchSnapshot(options.configPath, *watch)
+	} else {
+		snapshot, err = options.load()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	roots := lib.BuildDeviceTree(snapshot.Devices)
+	data := reportData{
+		Hostname: snapshot.Hostname,
+		Time:     snapshot.Time,
+		Devices:  snapshot.Devices,
+		Roots:    roots,
+		Warnings: lib.Warnings(roots),
+		Log:      snapshot.Log[max(0, len(snapshot.Log)-*logSize):],
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := write(out, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// watchSnapshot monitors the devices for a while so the report includes the events that happened meanwhile.
+func watchSnapshot(configPath string, duration time.Duration) (lib.Snapshot, error) {
+	if err := loadConfig(configPath); err != nil {
+		return lib.Snapshot{}, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	updates := make(chan []lib.Device, 1)
+	_, err := lib.Init(func(devices []lib.Device) {
+		if devices != nil {
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- devices
+		}
+	})
+	defer lib.Stop()
+	if err != nil {
+		return lib.Snapshot{}, err
+	}
+
+	var devices []lib.Device
+	for {
+		select {
+		case devices = <-updates:
+		case <-ctx.Done():
+			if devices == nil {
+				return lib.Snapshot{}, fmt.Errorf("no USB devices found")
+			}
+			return lib.NewSnapshot(devices), nil
+		}
+	}
+}
+
+var reportWriters = map[string]func(io.Writer, reportData) error{
+	"html":     writeReportHTML,
+	"markdown": writeReportMarkdown,
+}
+
+func writeReportHTML(w io.Writer, data reportData) error {
+	return reportTemplate.Execute(w, data)
+}
+
+func writeReportMarkdown(w io.Writer, data reportData) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# USB report for %s\n\n", data.Hostname)
+	fmt.Fprintf(&sb, "%d devices, captured %s\n\n", len(data.Devices), data.Time.Format("2006-01-02 15:04:05 MST"))
+
+	sb.WriteString("## Warnings\n\n")
+	if len(data.Warnings) == 0 {
+		sb.WriteString("No speed bottlenecks or power budget problems found.\n")
+	}
+	for _, warning := range data.Warnings {
+		fmt.Fprintf(&sb, "- %s (%s)\n", markdownEscape(warning.Message), warning.Device.PortPath())
+	}
+
+	sb.WriteString("\n## Tree\n\n```\n")
+	if err := cli.PrintTree(&sb, data.Roots); err != nil {
+		return err
+	}
+	sb.WriteString("```\n\n## Devices\n\n")
+	sb.WriteString("| Port | ID | Name | Speed | Class | Serial | USB | Power | Driver |\n")
+	sb.WriteString("|------|----|------|-------|-------|--------|-----|-------|--------|\n")
+	for _, device := range data.Devices {
+		power := ""
+		if device.MaxPower > 0 {
+			power = fmt.Sprintf("%d mA", device.MaxPower)
+		}
+		if device.SelfPowered {
+			power += " (self)"
+		}
+		fmt.Fprintf(&sb, "| %s | %s:%s | %s | %s | %s | %s | %s | %s | %s |\n",
+			cli.WithPortLabel(device.PortPath(), device.PortLabel), device.VendorID, device.ProductID,
+			markdownEscape(strings.TrimSpace(device.DisplayName())), strings.TrimSpace(cli.FormatSpeed(device.Speed)),
+			device.Class, markdownEscape(device.Serial), device.USBVersion, power, device.Driver)
+	}
+
+	sb.WriteString("\n## Event log\n\n")
+	if len(data.Log) == 0 {
+		sb.WriteString("No events were recorded.\n")
+	}
+	for _, log := range data.Log {
+		fmt.Fprintf(&sb, "- `%s` %s %s\n", log.Time.Format("15:04:05"), log.State,
+			markdownEscape(cli.WithPortLabel(log.Text, log.PortLabel)))
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownEscape escapes the characters that would break a Markdown table cell or list item.
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`").Replace(s)
+}
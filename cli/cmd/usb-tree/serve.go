@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runServe monitors the devices and serves them over HTTP until interrupted.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree serve [flags]")
+		fmt.Fprintln(flags.Output(), "Serves the devices, tree, log, metrics and a live event stream over HTTP until interrupted.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	listen := flags.String("listen", "127.0.0.1:8787", `address to listen on, host:port or "unix:" followed by a socket path`)
+	sinks := addSinkFlags(flags)
+	polling := addPollingFlags(flags)
+	debugLog := addDebugLogFlag(flags)
+	_ = flags.Parse(args)
+
+	return serve(*configPath, *listen, *debugLog, sinks, polling, func(server *lib.Server) http.Handler { return server })
+}
+
+// runExporter monitors the devices and serves only their metrics, for Prometheus to scrape.
+func runExporter(args []string) int {
+	flags := flag.NewFlagSet("exporter", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree exporter [flags]")
+		fmt.Fprintln(flags.Output(), "Serves Prometheus metrics about the devices on /metrics until interrupted.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	listen := flags.String("listen", "127.0.0.1:9808", `address to listen on, host:port or "unix:" followed by a socket path`)
+	polling := addPollingFlags(flags)
+	debugLog := addDebugLogFlag(flags)
+	_ = flags.Parse(args)
+
+	return serve(*configPath, *listen, *debugLog, &sinkOptions{}, polling, func(server *lib.Server) http.Handler {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", server)
+		return mux
+	})
+}
+
+// serve runs a lib.Server on the address until interrupted, exposing what handler returns for it, and forwards
+// device events to the system logs and MQTT broker selected by sinks. The devices are polled as set by polling,
+// and a debug log is written to debugLogPath if it is not empty.
+func serve(configPath, address, debugLogPath string, sinks *sinkOptions, polling *pollingFlags,
+	handler func(*lib.Server) http.Handler,
+) int {
+	if err := loadConfig(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := polling.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	closeDebugLog, err := openDebugLog(debugLogPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer closeDebugLog()
+	listener, err := lib.Listen(address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	forwarder, err := sinks.open()
+	if err != nil {
+		_ = listener.Close()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer forwarder.close()
+	server := lib.NewServer()
+	server.OnUpdate(forwarder.update)
+	go server.Run(ctx)
+
+	// Requests share ctx so that event streams end on shutdown instead of holding it up.
+	httpServer := &http.Server{
+		Handler:           handler(server),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving on %s\n", listener.Addr())
+	if err := httpServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// sinkOptions selects the system logs and the MQTT broker that device events are forwarded to.
+type sinkOptions struct {
+	journal bool
+	syslog  string
+
+	mqtt         string
+	mqttUser     string
+	mqttPassword string
+	mqttPrefix   string
+	mqttCA       string
+	mqttCert     string
+	mqttKey      string
+}
+
+// addSinkFlags adds the flags forwarding device events to the system logs and to an MQTT broker.
+func addSinkFlags(flags *flag.FlagSet) *sinkOptions {
+	options := &sinkOptions{}
+	flags.BoolVar(&options.journal, "journal", false, "also write device events to the systemd journal")
+	flags.StringVar(&options.syslog, "syslog", "", `also write device events to syslog at this address, e.g. "unix:/dev/log" or "udp:loghost:514"`)
+	flags.StringVar(&options.mqtt, "mqtt", "", `also publish devices and events to the MQTT broker at this URL, e.g. "mqtt://broker:1883" or "mqtts://broker"`)
+	flags.StringVar(&options.mqttUser, "mqtt-user", "", "user name for the MQTT broker")
+	flags.StringVar(&options.mqttPassword, "mqtt-password", os.Getenv("USB_TREE_MQTT_PASSWORD"),
+		"password for the MQTT broker, defaults to $USB_TREE_MQTT_PASSWORD")
+	flags.StringVar(&options.mqttPrefix, "mqtt-prefix", lib.DefaultMQTTPrefix, "first level of the MQTT topics")
+	flags.StringVar(&options.mqttCA, "mqtt-ca", "", "PEM file with the CA certificates verifying an mqtts:// broker")
+	flags.StringVar(&options.mqttCert, "mqtt-cert", "", "PEM file with a client certificate for an mqtts:// broker")
+	flags.StringVar(&options.mqttKey, "mqtt-key", "", "PEM file with the key of --mqtt-cert")
+	return options
+}
+
+// eventSinks forwards device events to the selected outputs until closed.
+type eventSinks struct {
+	publisher *lib.MQTTPublisher
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// open connects to the selected outputs and starts forwarding device events to them.
+func (o *sinkOptions) open() (*eventSinks, error) {
+	var sinks []lib.EventSink
+	closeAll := func() {
+		for _, sink := range sinks {
+			_ = sink.Close()
+		}
+	}
+
+	if o.journal {
+		sink, err := lib.NewJournalSink(lib.DefaultJournalSocket)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if o.syslog != "" {
+		sink, err := lib.NewSyslogSink(o.syslog)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	s := &eventSinks{done: make(chan struct{})}
+	if o.mqtt != "" {
+		tlsConfig, err := o.tlsConfig()
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		s.publisher, err = lib.NewMQTTPublisher(lib.MQTTOptions{
+			Broker:   o.mqtt,
+			Username: o.mqttUser,
+			Password: o.mqttPassword,
+			TLS:      tlsConfig,
+			Prefix:   o.mqttPrefix,
+		})
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sinks = append(sinks, s.publisher)
+	}
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	go func() {
+		defer close(s.done)
+		if len(sinks) > 0 {
+			lib.ForwardEvents(ctx, sinks...)
+		}
+		closeAll()
+	}()
+
+	return s, nil
+}
+
+// tlsConfig returns the TLS configuration for the MQTT broker, or nil for the defaults.
+func (o *sinkOptions) tlsConfig() (*tls.Config, error) {
+	if o.mqttCA == "" && o.mqttCert == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{}
+	if o.mqttCA != "" {
+		data, err := os.ReadFile(o.mqttCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", o.mqttCA)
+		}
+	}
+	if o.mqttCert != "" {
+		if o.mqttKey == "" {
+			return nil, errors.New("--mqtt-cert requires --mqtt-key")
+		}
+		certificate, err := tls.LoadX509KeyPair(o.mqttCert, o.mqttKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// update publishes the states of the devices to the MQTT broker, if any.
+func (s *eventSinks) update(devices []lib.Device) {
+	if s.publisher != nil {
+		_ = s.publisher.Update(devices)
+	}
+}
+
+// close stops forwarding and disconnects from the outputs.
+func (s *eventSinks) close() {
+	s.cancel()
+	<-s.done
+}
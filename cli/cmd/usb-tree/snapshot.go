@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runSnapshot saves the connected devices to a file that the other commands can read with --snapshot.
+func runSnapshot(args []string) int {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree snapshot [flags] <file.json>")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	devices, err := listDevices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := lib.SaveSnapshot(flags.Arg(0), lib.NewSnapshot(devices)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runTree prints the device tree once.
+func runTree(args []string) int {
+	flags := flag.NewFlagSet("tree", flag.ExitOnError)
+	options := addQueryFlags(flags)
+	format := flags.String("format", "text", "output format: text or lsusb-t")
+	_ = flags.Parse(args)
+
+	if code := checkFormat(*format, options.json, "text", "lsusb-t"); code != 0 {
+		return code
+	}
+
+	devices, matches, err := options.devices()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	roots := lib.BuildDeviceTree(devices).Filter(func(node *lib.TreeNode) bool { return matches(node.Device) })
+	switch {
+	case options.json:
+		err = writeJSON(os.Stdout, nonNil(roots))
+	case *format == "lsusb-t":
+		err = writeLsusbTree(os.Stdout, roots)
+	default:
+		err = cli.PrintTree(os.Stdout, roots)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
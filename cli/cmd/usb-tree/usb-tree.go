@@ -1,17 +1,105 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/AOzmond/usb-tree/cli"
+	"github.com/AOzmond/usb-tree/lib"
 )
 
+// commands maps subcommand names to their entry points. Each returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"list":      runList,
+	"tree":      runTree,
+	"inspect":   runInspect,
+	"events":    runEvents,
+	"wait":      runWait,
+	"snapshot":  runSnapshot,
+	"export":    runExport,
+	"report":    runReport,
+	"inventory": runInventory,
+	"serve":     runServe,
+	"exporter":  runExporter,
+	"check":     runCheck,
+	"audit":     runAudit,
+}
+
 func main() {
-	teaProgram := tea.NewProgram(cli.InitialModel())
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
+	os.Exit(runUI(os.Args[1:]))
+}
+
+// runUI starts the interactive tree view, the default when no subcommand is given.
+func runUI(args []string) int {
+	flags := flag.NewFlagSet("usb-tree", flag.ExitOnError)
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	baselinePath := flags.String("baseline", "", "mark deviations from this baseline file in the tree")
+	connect := flags.String("connect", "", `show the devices of "usb-tree serve" daemons at these comma-separated addresses instead`)
+	polling := addPollingFlags(flags)
+	debugLog := addDebugLogFlag(flags)
+	_ = flags.Parse(args)
+
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := polling.apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	closeDebugLog, err := openDebugLog(*debugLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer closeDebugLog()
+
+	options := cli.Options{ConfigPath: *configPath}
+	if *connect != "" {
+		options.Source = lib.ConnectAll(strings.Split(*connect, ","))
+	}
+	if *baselinePath != "" {
+		baseline, err := lib.LoadBaseline(*baselinePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: reading baseline %s: %v\n", *baselinePath, err)
+			return 1
+		}
+		options.Baseline = &baseline
+	}
+
+	teaProgram := tea.NewProgram(cli.InitialModel(options))
 	if _, err := teaProgram.Run(); err != nil {
-		fmt.Printf("Error: %v", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
 	}
+
+	return 0
+}
+
+// loadConfig reads the configuration file and makes it and its polling current.
+func loadConfig(path string) error {
+	cfg, err := lib.LoadConfig(path)
+	if err != nil {
+		return fmt.Errorf("reading config %s: %w", path, err)
+	}
+	lib.SetConfig(cfg)
+	lib.SetPolling(cfg.Polling)
+
+	return nil
+}
+
+// listDevices enumerates the connected devices once.
+func listDevices() ([]lib.Device, error) {
+	_, devices, err := lib.Refresh()
+
+	return devices, err
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// runWait blocks until a device shows up, or disappears with --removed.
+func runWait(args []string) int {
+	flags := flag.NewFlagSet("wait", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: usb-tree wait --vid <vid> [--pid <pid>] [--serial <serial>] [--removed] [--timeout 30s]")
+		fmt.Fprintln(flags.Output(), "Exits with 0 once the device is present (or gone), 1 on timeout and 2 on errors.")
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", lib.DefaultConfigPath(), "path to the configuration file")
+	var matcher lib.DeviceMatcher
+	flags.StringVar(&matcher.VendorID, "vid", "", "vendor ID of the device")
+	flags.StringVar(&matcher.ProductID, "pid", "", "product ID of the device")
+	flags.StringVar(&matcher.Serial, "serial", "", "serial number of the device")
+	flags.StringVar(&matcher.Port, "port", "", "port path of the device, e.g. 1-3.2")
+	removed := flags.Bool("removed", false, "wait for the device to be removed instead")
+	timeout := flags.Duration("timeout", 0, "give up after this long (default: wait forever)")
+	debugLog := addDebugLogFlag(flags)
+	_ = flags.Parse(args)
+
+	if matcher.IsZero() {
+		flags.Usage()
+		return 2
+	}
+	if err := loadConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	closeDebugLog, err := openDebugLog(*debugLog)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	defer closeDebugLog()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	state := lib.StateAdded
+	if *removed {
+		state = lib.StateRemoved
+	}
+
+	device, err := lib.WaitFor(ctx, matcher, state)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(os.Stderr, "Timed out waiting for %s\n", matcher)
+		return 1
+	}
+	if errors.Is(err, context.Canceled) {
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if device.Bus != 0 {
+		fmt.Printf("%s %s:%s %s\n", device.PortPath(), device.VendorID, device.ProductID, device.DisplayName())
+		if device.DevNode != "" && !*removed {
+			fmt.Println(device.DevNode)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// maxHealthErrorWidth bounds the length of the error shown in the status line, leaving room for the help.
+const maxHealthErrorWidth = 48
+
+// healthStatus describes the health of the monitoring for the status line. The connections to several
+// daemons are described by hostStatus instead.
+func (m *Model) healthStatus() string {
+	if m.hosts != nil {
+		return ""
+	}
+
+	status := m.source.Health()
+	text := " Health: " + string(status.State)
+	if status.State == lib.HealthFailing {
+		text += " (" + middleTruncate(status.Error, maxHealthErrorWidth) + ")"
+		if !status.NextRetry.IsZero() {
+			text += ", retry at " + status.NextRetry.Format("15:04:05")
+		}
+	}
+
+	return healthStyles[status.State].Render(text)
+}
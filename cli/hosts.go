@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// buildTree arranges the devices as a tree, below a node per host when showing several daemons.
+func (m *Model) buildTree(devices []lib.Device) []*lib.TreeNode {
+	if m.hosts == nil {
+		return lib.BuildDeviceTree(devices)
+	}
+
+	statuses := m.hosts.Hosts()
+	hosts := make([]string, len(statuses))
+	for i, status := range statuses {
+		hosts[i] = status.Host
+	}
+
+	return lib.BuildHostTree(hosts, devices)
+}
+
+// hostStatus summarises the connections to the daemons for the status line.
+func (m *Model) hostStatus() string {
+	if m.hosts == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, status := range m.hosts.Hosts() {
+		sb.WriteString(hostStyles[status.State].Render(" " + status.Host + ": " + string(status.State)))
+	}
+
+	return sb.String()
+}
+
+// hostInfo describes the connection to a host in place of the tooltip of a device.
+func (m *Model) hostInfo(host string) string {
+	state := lib.ConnectionConnecting
+	if m.hosts != nil {
+		for _, status := range m.hosts.Hosts() {
+			if status.Host == host {
+				state = status.State
+			}
+		}
+	}
+
+	devices := 0
+	for _, root := range m.roots {
+		if root.Host == host {
+			devices = countDevices(root.Children)
+		}
+	}
+
+	return windowStyle.Foreground(hostTextColor).Render("Host: ", host) + "\n" +
+		hostStyles[state].Render("Connection: ", string(state)) + "\n" +
+		windowStyle.Foreground(nameTextColor).Render(strconv.Itoa(devices)+" devices")
+}
+
+// countDevices returns the number of devices in the trees.
+func countDevices(nodes []*lib.TreeNode) int {
+	count := 0
+	for range lib.Tree(nodes).Flatten(nil) {
+		count++
+	}
+
+	return count
+}
@@ -18,6 +18,8 @@ type keyMap struct {
 	Refresh      key.Binding
 	Collapse     key.Binding
 	Expand       key.Binding
+	EditAlias    key.Binding
+	Search       key.Binding
 }
 
 var keys = keyMap{
@@ -61,11 +63,19 @@ var keys = keyMap{
 		key.WithKeys("right", "l"),
 		key.WithHelp("→/l", "expand"),
 	),
+	EditAlias: key.NewBinding(
+		key.WithKeys("a"),
+		key.WithHelp("a", "edit alias"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search"),
+	),
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Quit, k.Instructions, k.SwitchFocus, k.Refresh, k.Up, k.Down, k.PageUp, k.PageDown, k.Collapse, k.Expand},
+		{k.Quit, k.Instructions, k.SwitchFocus, k.Refresh, k.Up, k.Down, k.PageUp, k.PageDown, k.Collapse, k.Expand, k.EditAlias, k.Search},
 	}
 }
 
@@ -80,7 +90,7 @@ func (m Model) instructionsView() string {
 	instructionsHelp.SetWidth(max(0, m.windowWidth-8))
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
-		instructionsHelp.View(keys),
+		instructionsHelp.View(m.keys),
 	)
 
 	boxWidth := lipgloss.Width(content)
@@ -43,14 +43,20 @@ func (m *Model) formatLogEntry(log lib.Log) string {
 	} else if log.State == lib.StateAdded {
 		stateStyle = addedLogStyle
 		stateString = "+"
+	} else if log.State == lib.StateViolation {
+		stateStyle = violationLogStyle
+		stateString = "!"
 	}
 	rhsString := formatSpeed(log.Speed)
 	logPrefix := log.Time.Format("15:04:05") + " " + stateString + " "
+	if log.Host != "" {
+		logPrefix += "[" + log.Host + "] "
+	}
 	availableForName := m.logViewport.Width() - lipgloss.Width(logPrefix) - lipgloss.Width(rhsString)
 	if availableForName < 0 {
 		availableForName = 0
 	}
-	name := middleTruncate(log.Text, availableForName)
+	name := middleTruncate(WithPortLabel(log.Text, log.PortLabel), availableForName)
 	lhsString := stateStyle.Render(logPrefix + name)
 
 	paddingSize := m.logViewport.Width() - lipgloss.Width(rhsString) - lipgloss.Width(lhsString)
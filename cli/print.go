@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// PrintTree writes the device tree as plain text, with the same branches and speed column as the interactive view.
+func PrintTree(w io.Writer, roots []*lib.TreeNode) error {
+	var names, speeds []string
+	lib.Tree(roots).Walk(func(node *lib.TreeNode, ancestors []*lib.TreeNode) bool {
+		names = append(names, treePrefix(branches(node, ancestors))+WithPortLabel(node.DisplayName(), node.PortLabel))
+		speeds = append(speeds, formatSpeed(node.Speed))
+		return true
+	})
+
+	nameWidth := 0
+	for _, name := range names {
+		nameWidth = max(nameWidth, lipgloss.Width(name))
+	}
+	for i, name := range names {
+		padding := strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", name, padding, speeds[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WithPortLabel appends the physical port label, if any, to a device name or log entry.
+func WithPortLabel(name, portLabel string) string {
+	if portLabel == "" {
+		return name
+	}
+	return name + " [" + portLabel + "]"
+}
+
+// FormatSpeed formats a device speed in Mbps with a uniform width and unit, as shown in the tree.
+func FormatSpeed(speed string) string {
+	return formatSpeed(speed)
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+	"github.com/AOzmond/usb-tree/lib"
+)
+
+// newSearchInput creates the text input used to search the tree.
+func newSearchInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "Search: "
+	input.Placeholder = `text, vid:pid or a filter such as class:hid name~"keyboard"`
+	input.CharLimit = 128
+	return input
+}
+
+// startSearch opens the search prompt, keeping the previous query so enter finds its next match.
+func (m *Model) startSearch() tea.Cmd {
+	m.searching = true
+	m.searchError = searchErrorFor(m.searchInput.Value())
+	m.searchInput.CursorEnd()
+	m.searchInput.SetWidth(max(0, m.windowWidth-borderSpacing-(2*horizontalPadding)-len(m.searchInput.Prompt)-1))
+	return m.searchInput.Focus()
+}
+
+// updateSearch routes key presses to the search prompt while it is open.
+func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, aliasEditorKeys.Cancel):
+		m.closeSearch()
+		return m, nil
+
+	case key.Matches(msg, aliasEditorKeys.Save):
+		filter, err := lib.ParseFilter(m.searchInput.Value())
+		if err != nil {
+			m.searchError = err.Error()
+			return m, nil
+		}
+		if !m.selectNextMatch(filter) {
+			m.searchError = "No device matches"
+			return m, nil
+		}
+		m.closeSearch()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.searchError = searchErrorFor(m.searchInput.Value())
+	return m, cmd
+}
+
+// searchErrorFor returns the parse error of the query, shown while it is typed, or "" if it is valid.
+func searchErrorFor(query string) string {
+	if _, err := lib.ParseFilter(query); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// closeSearch hides the search prompt.
+func (m *Model) closeSearch() {
+	m.searching = false
+	m.searchError = ""
+	m.searchInput.Blur()
+}
+
+// selectNextMatch moves the cursor to the first device after it matching the filter, wrapping around and
+// expanding collapsed hubs and hosts on the way. It reports whether a device matched.
+func (m *Model) selectNextMatch(filter lib.Filter) bool {
+	if filter.IsZero() {
+		return false
+	}
+
+	tree := lib.Tree(m.roots)
+	var nodes []*lib.TreeNode
+	for _, node := range tree.Flatten(nil) {
+		nodes = append(nodes, node)
+	}
+
+	start := 0
+	if m.selectedDevice != nil {
+		for i, node := range nodes {
+			if node.Key() == m.selectedDevice.Key() {
+				start = i + 1
+			}
+		}
+	}
+
+	for i := range nodes {
+		node := nodes[(start+i)%len(nodes)]
+		if node.IsHost() || !filter.Matches(node.Device) {
+			continue
+		}
+		for _, ancestor := range tree.Ancestors(node.Key()) {
+			delete(m.collapsed, ancestor.Key())
+		}
+		m.updateNodeCount()
+		m.treeCursor, _ = m.visibleNodeIndexByKey(node.Key())
+		m.updateSelectedDevice()
+		m.refreshContent()
+		m.scrollToCursor()
+		return true
+	}
+
+	return false
+}
+
+// searchView renders the search prompt in place of the tooltip.
+func (m *Model) searchView() string {
+	content := windowStyle.Foreground(nameTextColor).Render("Enter selects the next matching device") +
+		"\n" + m.searchInput.View()
+	if m.searchError != "" {
+		width := max(0, m.windowWidth-borderSpacing-(2*horizontalPadding))
+		content += "\n" + windowStyle.Foreground(removedStateColor).Render(middleTruncate(m.searchError, width))
+	}
+	return content
+}
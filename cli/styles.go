@@ -1,6 +1,9 @@
 package cli
 
-import "charm.land/lipgloss/v2"
+import (
+	"charm.land/lipgloss/v2"
+	"github.com/AOzmond/usb-tree/lib"
+)
 
 const (
 	gray      = "#888888"
@@ -16,6 +19,7 @@ const (
 	coralRed  = "#FF6B6B"
 	paleGreen = "#98FB98"
 	plum      = "#DDA0DD"
+	steelBlue = "#B0C4DE"
 )
 
 var (
@@ -35,8 +39,19 @@ var (
 	vidTextColor              = lipgloss.Color(coralRed)
 	pidTextColor              = lipgloss.Color(paleGreen)
 	nameTextColor             = lipgloss.Color(gray)
+	aliasTextColor            = lipgloss.Color(white)
+	portTextColor             = lipgloss.Color(steelBlue)
 	logAddedColor             = lipgloss.Color(green)
 	logRemovedColor           = lipgloss.Color(red)
+	baselineOKColor           = lipgloss.Color(green)
+	baselineDeviationColor    = lipgloss.Color(gold)
+	violationColor            = lipgloss.Color(hotPink)
+	hostTextColor             = lipgloss.Color(white)
+	hostConnectedColor        = lipgloss.Color(green)
+	hostStaleColor            = lipgloss.Color(gold)
+	hostErrorColor            = lipgloss.Color(red)
+	healthOKColor             = lipgloss.Color(green)
+	healthFailingColor        = lipgloss.Color(red)
 )
 
 var (
@@ -65,5 +80,32 @@ var (
 	removedLogStyle = windowStyle.
 			Foreground(logRemovedColor)
 
+	violationLogStyle = windowStyle.
+				Foreground(violationColor)
+
 	stateStyle = windowStyle
+
+	aliasStyle = windowStyle.
+			Foreground(aliasTextColor).
+			Bold(true)
+
+	baselineOKStyle = windowStyle.
+			Foreground(baselineOKColor)
+
+	baselineDeviationStyle = windowStyle.
+				Foreground(baselineDeviationColor)
+
+	hostStyles = map[lib.ConnectionState]lipgloss.Style{
+		lib.ConnectionConnecting: windowStyle.Foreground(nameTextColor),
+		lib.ConnectionConnected:  windowStyle.Foreground(hostConnectedColor),
+		lib.ConnectionStale:      windowStyle.Foreground(hostStaleColor),
+		lib.ConnectionError:      windowStyle.Foreground(hostErrorColor),
+	}
+
+	healthStyles = map[lib.HealthState]lipgloss.Style{
+		lib.HealthStarting: windowStyle.Foreground(nameTextColor),
+		lib.HealthOK:       windowStyle.Foreground(healthOKColor),
+		lib.HealthFailing:  windowStyle.Foreground(healthFailingColor),
+		lib.HealthStopped:  windowStyle.Foreground(nameTextColor),
+	}
 )
@@ -1,6 +1,9 @@
 package cli
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 // getSelectedDeviceInfo returns formatted device info for the currently selected node
 func (m *Model) getSelectedDeviceInfo() string {
@@ -8,6 +11,9 @@ func (m *Model) getSelectedDeviceInfo() string {
 		return ""
 	}
 	node := m.selectedDevice
+	if node.IsHost() {
+		return m.hostInfo(node.Host)
+	}
 
 	busStyle := windowStyle.Foreground(busTextColor)
 	deviceStyle := windowStyle.Foreground(deviceTextColor)
@@ -15,16 +21,41 @@ func (m *Model) getSelectedDeviceInfo() string {
 	pidStyle := windowStyle.Foreground(pidTextColor)
 	nameStyle := windowStyle.Foreground(nameTextColor)
 	linkStyle := windowStyle.Foreground(linkTextColor)
+	portStyle := windowStyle.Foreground(portTextColor)
 
 	busString := busStyle.Render("Bus: ", strconv.Itoa(node.Bus))
 	deviceString := deviceStyle.Render(" Device: ", strconv.Itoa(node.DevNum))
 	vidString := vidStyle.Render(" VID: ", node.VendorID)
 	pidString := pidStyle.Render(" PID: ", node.ProductID)
+	portText := node.PortPath()
+	if node.PortLabel != "" {
+		portText += " (" + node.PortLabel + ")"
+	}
+	portString := portStyle.Render(" Port: ", portText)
 
-	deviceInfo := busString + deviceString + vidString + pidString
+	deviceInfo := busString + deviceString + vidString + pidString + portString
+	if node.Host != "" {
+		deviceInfo = windowStyle.Foreground(hostTextColor).Render("Host: ", node.Host, " ") + deviceInfo
+	}
 
 	nameString := nameStyle.Render(node.Name)
+	if node.Alias != "" {
+		nameString = aliasStyle.Render(node.Alias) + nameStyle.Render(" ("+strings.TrimSpace(node.Name)+")")
+	}
+	if node.Note != "" {
+		nameString += nameStyle.Render(" - " + node.Note)
+	}
 	linkString := linkStyle.Render(getDbAddress(node.VendorID, node.ProductID))
+	if deviations := m.deviationMessages(node); deviations != "" {
+		linkString = baselineDeviationStyle.Render("Baseline: " + deviations)
+	}
+	if len(node.Violations) > 0 {
+		messages := make([]string, len(node.Violations))
+		for i, violation := range node.Violations {
+			messages[i] = violation.Message
+		}
+		linkString = violationLogStyle.Render("Policy violation: " + strings.Join(messages, "; "))
+	}
 
 	tooltipString := deviceInfo + "\n" + nameString + "\n" + linkString
 
@@ -20,66 +20,34 @@ func waitForUpdate(sub chan []lib.Device) tea.Cmd {
 
 // updateNodeCount updateChan the nodeCount based on visible devices.
 func (m *Model) updateNodeCount() {
-	idx := 0
-	for _, root := range m.roots {
-		idx = m.countVisibleNodes(root, idx)
+	m.nodeCount = 0
+	for range lib.Tree(m.roots).Flatten(m.expanded) {
+		m.nodeCount++
 	}
-	m.nodeCount = idx
 }
 
-// countVisibleNodes recursively counts the number of visible nodes under the given node
-func (m *Model) countVisibleNodes(node *lib.TreeNode, currentIdx int) int {
-	idx := currentIdx + 1
-	if !m.collapsed[node.Key()] {
-		for _, child := range node.Children {
-			idx = m.countVisibleNodes(child, idx)
-		}
-	}
-	return idx
+// expanded reports whether the children of the node are shown.
+func (m *Model) expanded(node *lib.TreeNode) bool {
+	return !m.collapsed[node.Key()]
 }
 
 // renderTree renders the tree content to a string
 func (m *Model) renderTree() string {
-	var deviceTreeSb strings.Builder
+	var lines []string
 	idx := 0
 
-	for _, root := range m.roots {
-		lines, nextIdx := m.renderNode(root, idx, []bool{})
-		deviceTreeSb.WriteString(strings.Join(lines, "\n"))
-		if nextIdx < m.nodeCount {
-			deviceTreeSb.WriteByte('\n')
-		}
-		idx = nextIdx
-	}
-
-	return deviceTreeSb.String()
-}
+	lib.Tree(m.roots).Walk(func(node *lib.TreeNode, ancestors []*lib.TreeNode) bool {
+		rowStyle, contentStyle := m.getNodeStyles(node, idx == m.treeCursor)
+		indicators, contentStyle := m.getNodeIndicators(node, contentStyle)
+		prefixStr := treePrefix(branches(node, ancestors))
+		lines = append(lines, m.renderNodeLine(node, prefixStr, indicators, rowStyle, contentStyle))
+		idx++
 
-// renderNode recursively renders a node and its children
-func (m *Model) renderNode(node *lib.TreeNode, currentIdx int, continues []bool) ([]string, int) {
-	isSelected := currentIdx == m.treeCursor
-	idx := currentIdx + 1
-
-	rowStyle, contentStyle := m.getNodeStyles(node, isSelected)
-	indicators, contentStyle := m.getNodeIndicators(node, contentStyle)
-	prefixStr := m.buildTreePrefix(continues)
-
-	line := m.renderNodeLine(node, prefixStr, indicators, rowStyle, contentStyle)
-	renderedDevices := []string{line}
-
-	// Only render children if not collapsed
-	if !m.collapsed[node.Key()] {
-		lastIdx := len(node.Children) - 1
-		for i, child := range node.Children {
-			isLast := i == lastIdx
-			childContinues := append(continues, !isLast)
-			var childLines []string
-			childLines, idx = m.renderNode(child, idx, childContinues)
-			renderedDevices = append(renderedDevices, childLines...)
-		}
-	}
+		// Only render children if not collapsed
+		return m.expanded(node)
+	})
 
-	return renderedDevices, idx
+	return strings.Join(lines, "\n")
 }
 
 // updateSelectedDevice keeps the selected device in sync with the cursor.
@@ -94,28 +62,10 @@ func (m *Model) updateSelectedDevice() {
 
 // visibleNodeIndexByKey finds a device's current cursor index.
 func (m *Model) visibleNodeIndexByKey(key string) (int, bool) {
-	index := 0
-	var visit func(*lib.TreeNode) (int, bool)
-	visit = func(node *lib.TreeNode) (int, bool) {
-		if node.Device.Key() == key {
+	for index, node := range lib.Tree(m.roots).Flatten(m.expanded) {
+		if node.Key() == key {
 			return index, true
 		}
-		index++
-		if m.collapsed[node.Key()] {
-			return 0, false
-		}
-		for _, child := range node.Children {
-			if foundIndex, found := visit(child); found {
-				return foundIndex, true
-			}
-		}
-		return 0, false
-	}
-
-	for _, root := range m.roots {
-		if foundIndex, found := visit(root); found {
-			return foundIndex, true
-		}
 	}
 	return 0, false
 }
@@ -128,6 +78,9 @@ func (m *Model) getNodeStyles(node *lib.TreeNode, isSelected bool) (lipgloss.Sty
 	}
 
 	contentStyle := rowStyle
+	if node.Color != "" && !isSelected {
+		contentStyle = contentStyle.Foreground(lipgloss.Color(node.Color))
+	}
 	switch node.State {
 	case lib.StateAdded:
 		contentStyle = contentStyle.Foreground(addedStateColor)
@@ -160,11 +113,38 @@ func (m *Model) getNodeIndicators(node *lib.TreeNode, contentStyle lipgloss.Styl
 		statusPrefix = "- "
 	}
 
+	if len(m.baselineDeviations[node.Key()]) > 0 {
+		statusPrefix += "! "
+		if node.State == lib.StateNormal {
+			contentStyle = contentStyle.Foreground(baselineDeviationColor)
+		}
+	}
+	if len(node.Violations) > 0 {
+		statusPrefix += "✗ "
+		if node.State == lib.StateNormal {
+			contentStyle = contentStyle.Foreground(violationColor)
+		}
+	}
+
 	return childrenIndicator + statusPrefix, contentStyle
 }
 
-// buildTreePrefix generates a string representation of a tree structure prefix based on the provided continues slice.
-func (m *Model) buildTreePrefix(continues []bool) string {
+// branches returns for each level below the root whether the node or its ancestor at that level has siblings
+// after it, as treePrefix expects.
+func branches(node *lib.TreeNode, ancestors []*lib.TreeNode) []bool {
+	continues := make([]bool, len(ancestors))
+	for i, parent := range ancestors {
+		child := node
+		if i+1 < len(ancestors) {
+			child = ancestors[i+1]
+		}
+		continues[i] = parent.Children[len(parent.Children)-1] != child
+	}
+	return continues
+}
+
+// treePrefix generates a string representation of a tree structure prefix based on the provided continues slice.
+func treePrefix(continues []bool) string {
 	var prefix strings.Builder
 	for i := 0; i < len(continues); i++ {
 		if i == len(continues)-1 {
@@ -187,7 +167,7 @@ func (m *Model) buildTreePrefix(continues []bool) string {
 // renderNodeLine generates a formatted string representing a tree node line with styles, truncation, and aligned elements.
 func (m *Model) renderNodeLine(node *lib.TreeNode, prefixStr, indicators string, rowStyle, contentStyle lipgloss.Style) string {
 	totalWidth := m.treeViewport.Width()
-	name := strings.TrimSpace(node.Name)
+	name := WithPortLabel(node.DisplayName(), node.PortLabel)
 	speed := formatSpeed(node.Speed)
 
 	speedWidth := lipgloss.Width(speed)
@@ -283,88 +263,38 @@ func (m *Model) scrollDownToCursor() {
 
 // getNodeAtCursor returns the TreeNode at the current cursor position
 func (m *Model) getNodeAtCursor() *lib.TreeNode {
-	idx := 0
-	for _, root := range m.roots {
-		node, newIdx := m.findNodeAtIndex(root, idx, m.treeCursor)
-		if node != nil {
+	for index, node := range lib.Tree(m.roots).Flatten(m.expanded) {
+		if index == m.treeCursor {
 			return node
 		}
-		idx = newIdx
 	}
 	return nil
 }
 
-// findNodeAtIndex recursively searches for the node at the target index
-func (m *Model) findNodeAtIndex(node *lib.TreeNode, currentIdx int, targetIdx int) (*lib.TreeNode, int) {
-	if currentIdx == targetIdx {
-		return node, currentIdx + 1
-	}
-
-	idx := currentIdx + 1
-
-	if m.collapsed[node.Key()] {
-		return nil, idx
-	}
-
-	for _, child := range node.Children {
-		found, newIdx := m.findNodeAtIndex(child, idx, targetIdx)
-		if found != nil {
-			return found, newIdx
-		}
-		idx = newIdx
-	}
-
-	return nil, idx
-}
-
-// hasChangedChild recursively checks if any child of the node has a status update
+// hasChangedChild checks if any descendant of the node has a status update
 func (m *Model) hasChangedChild(node *lib.TreeNode) bool {
 	for _, child := range node.Children {
-		if child.State != lib.StateNormal {
-			return true
-		}
-		if m.hasChangedChild(child) {
-			return true
+		for descendant := range child.Subtree() {
+			if descendant.State != lib.StateNormal {
+				return true
+			}
 		}
 	}
 	return false
 }
 
-// checkOffscreenChanges returns whether there are changes (added/removed) above and below the visible viewport
+// checkOffscreenChanges returns whether there are changes (added/removed) above and below the visible viewport,
+// counting those within collapsed nodes
 func (m *Model) checkOffscreenChanges() (above bool, below bool) {
-	idx := 0
-	for _, root := range m.roots {
-		above, below, idx = m.checkNodeOffscreenChanges(root, idx, above, below)
-	}
-	return above, below
-}
-
-func (m *Model) checkNodeOffscreenChanges(node *lib.TreeNode, currentIdx int, above bool, below bool) (bool, bool, int) {
-	if node.State != lib.StateNormal {
-		if currentIdx < m.treeViewport.YOffset() {
+	for index, node := range lib.Tree(m.roots).Flatten(m.expanded) {
+		if node.State == lib.StateNormal && (m.expanded(node) || !m.hasChangedChild(node)) {
+			continue
+		}
+		if index < m.treeViewport.YOffset() {
 			above = true
-		} else if currentIdx >= m.treeViewport.YOffset()+m.treeViewport.Height() {
+		} else if index >= m.treeViewport.YOffset()+m.treeViewport.Height() {
 			below = true
 		}
 	}
-
-	// check children within collapsed nodes
-	if m.collapsed[node.Key()] {
-		if m.hasChangedChild(node) {
-			if currentIdx < m.treeViewport.YOffset() {
-				above = true
-			} else if currentIdx >= m.treeViewport.YOffset()+m.treeViewport.Height() {
-				below = true
-			}
-		}
-		return above, below, currentIdx + 1
-	}
-
-	// check children within non-collapsed nodes
-	idx := currentIdx + 1
-	for _, child := range node.Children {
-		above, below, idx = m.checkNodeOffscreenChanges(child, idx, above, below)
-	}
-
-	return above, below, idx
+	return above, below
 }
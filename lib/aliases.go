@@ -0,0 +1,74 @@
+package lib
+
+import (
+	"slices"
+	"strings"
+)
+
+// An Alias attaches a friendly name, a note and a display color to the Devices selected by Match.
+type Alias struct {
+	Match DeviceMatcher `yaml:"match" json:"match"`
+	Alias string        `yaml:"alias,omitempty" json:"alias,omitempty"`
+	Note  string        `yaml:"note,omitempty" json:"note,omitempty"`
+	Color string        `yaml:"color,omitempty" json:"color,omitempty"`
+}
+
+// FindAlias returns the first Alias whose matcher selects the Device.
+func (c Config) FindAlias(device Device) (Alias, bool) {
+	for _, alias := range c.Aliases {
+		if alias.Match.Matches(device) {
+			return alias, true
+		}
+	}
+
+	return Alias{}, false
+}
+
+// SetAlias sets the alias of the Device in the entry matching it alone, as MatcherFor does, so that entries
+// shared with other Devices are left alone. A new entry goes before the first one matching the Device, which it
+// overrides, and keeps its note and color. An empty alias removes an entry that carries no note or color and
+// overrides no other entry.
+func (c *Config) SetAlias(device Device, alias string) {
+	c.Aliases = slices.Clone(c.Aliases)
+
+	match := MatcherFor(device)
+	entry := Alias{Match: match}
+	first := slices.IndexFunc(c.Aliases, func(a Alias) bool { return a.Match.Matches(device) })
+	if i := slices.IndexFunc(c.Aliases, func(a Alias) bool { return a.Match == match }); i >= 0 {
+		entry = c.Aliases[i]
+		c.Aliases = slices.Delete(c.Aliases, i, i+1)
+	} else if first >= 0 {
+		entry.Note, entry.Color = c.Aliases[first].Note, c.Aliases[first].Color
+	}
+	entry.Alias = alias
+
+	if _, overrides := c.FindAlias(device); entry == (Alias{Match: match}) && !overrides {
+		return
+	}
+	if first < 0 {
+		c.Aliases = append(c.Aliases, entry)
+		return
+	}
+	c.Aliases = slices.Insert(c.Aliases, first, entry)
+}
+
+// applyAliases copies the alias, note and color of the first matching entry onto the Device.
+func (d *Device) applyAliases(cfg Config) {
+	alias, ok := cfg.FindAlias(*d)
+	if !ok {
+		return
+	}
+
+	d.Alias = alias.Alias
+	d.Note = alias.Note
+	d.Color = alias.Color
+}
+
+// DisplayName returns the Device alias when one is set, otherwise its name.
+func (d *Device) DisplayName() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+
+	return strings.TrimSpace(d.Name)
+}
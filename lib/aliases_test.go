@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var probe = Device{Path: []int{3, 2}, Name: "SEGGER J-Link", VendorID: "1366", ProductID: "0105", Speed: "12", Bus: 1, Serial: "000123", State: StateNormal}
+
+func TestPortPath(t *testing.T) {
+	assert.Equal(t, "1-3.2", probe.PortPath())
+	assert.Equal(t, "usb1", device4.PortPath())
+	assert.Equal(t, "1-1", device1.PortPath())
+}
+
+func TestConfig_SetAlias(t *testing.T) {
+	var cfg Config
+
+	cfg.SetAlias(probe, "J-Link #3")
+	assert.Len(t, cfg.Aliases, 1)
+	alias, found := cfg.FindAlias(probe)
+	assert.True(t, found)
+	assert.Equal(t, "J-Link #3", alias.Alias)
+
+	cfg.SetAlias(probe, "J-Link #4")
+	assert.Len(t, cfg.Aliases, 1, "editing an alias should not add an entry")
+	alias, _ = cfg.FindAlias(probe)
+	assert.Equal(t, "J-Link #4", alias.Alias)
+
+	cfg.SetAlias(probe, "")
+	assert.Empty(t, cfg.Aliases, "clearing an alias without a note should remove the entry")
+}
+
+func TestConfig_SetAliasKeepsNote(t *testing.T) {
+	cfg := Config{Aliases: []Alias{{Match: MatcherFor(probe), Alias: "old", Note: "rack 2"}}}
+	original := cfg.Aliases
+
+	cfg.SetAlias(probe, "")
+	assert.Len(t, cfg.Aliases, 1)
+	assert.Equal(t, "rack 2", cfg.Aliases[0].Note)
+	assert.Equal(t, "old", original[0].Alias, "SetAlias should not modify a shared Config")
+}
+
+func TestConfig_SetAliasLeavesSharedEntries(t *testing.T) {
+	shared := Alias{Match: DeviceMatcher{VendorID: "1366"}, Alias: "J-Link", Color: "#ff8800"}
+	cfg := Config{Aliases: []Alias{shared}}
+	other := probe
+	other.Serial = "000124"
+
+	cfg.SetAlias(probe, "J-Link #3")
+	assert.Equal(t, []Alias{{Match: MatcherFor(probe), Alias: "J-Link #3", Color: "#ff8800"}, shared}, cfg.Aliases,
+		"the new entry should go before the shared one and keep its color")
+	alias, _ := cfg.FindAlias(probe)
+	assert.Equal(t, "J-Link #3", alias.Alias)
+	alias, _ = cfg.FindAlias(other)
+	assert.Equal(t, "J-Link", alias.Alias, "the other probe should keep the shared alias")
+
+	cfg.SetAlias(other, "")
+	assert.Len(t, cfg.Aliases, 3, "clearing the alias of one probe should not remove the shared entry")
+	alias, _ = cfg.FindAlias(other)
+	assert.Empty(t, alias.Alias)
+	alias, _ = cfg.FindAlias(probe)
+	assert.Equal(t, "J-Link #3", alias.Alias)
+
+	cfg.SetAlias(probe, "")
+	alias, _ = cfg.FindAlias(probe)
+	assert.Empty(t, alias.Alias)
+	assert.Contains(t, cfg.Aliases, shared)
+}
+
+func TestApplyAliases(t *testing.T) {
+	cfg := Config{Aliases: []Alias{
+		{Match: DeviceMatcher{Serial: "000123"}, Alias: "J-Link #3", Note: "bench A", Color: "#ff8800"},
+		{Match: DeviceMatcher{VendorID: "1366"}, Alias: "Any J-Link"},
+	}}
+
+	d := probe
+	d.applyAliases(cfg)
+	assert.Equal(t, "J-Link #3", d.Alias)
+	assert.Equal(t, "bench A", d.Note)
+	assert.Equal(t, "#ff8800", d.Color)
+	assert.Equal(t, "J-Link #3", d.DisplayName())
+
+	unmatched := device1
+	unmatched.applyAliases(cfg)
+	assert.Equal(t, "Device 1", unmatched.DisplayName())
+}
+
+func TestDeviceDiff_AliasChange(t *testing.T) {
+	fakeRefresh([]Device{probe})
+	aliased := probe
+	aliased.Alias = "J-Link #3"
+
+	changed, merged := deviceDiff([]Device{aliased}, time.Now())
+	assert.True(t, changed, "an alias change should be reported")
+	assert.Equal(t, "J-Link #3", merged[0].Alias)
+	assert.Equal(t, StateNormal, merged[0].State)
+}
+
+func TestDeviceLogUsesAlias(t *testing.T) {
+	fakeRefresh([]Device{device1})
+	logs = nil
+	aliased := probe
+	aliased.Alias = "J-Link #3"
+
+	deviceDiff([]Device{device1, aliased}, time.Now())
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "J-Link #3", logs[0].Text)
+}
@@ -0,0 +1,239 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Baseline lists the Devices expected on a machine, such as the probes and boards of a test rig.
+// Devices selected by an Ignore matcher, and root hubs, are never reported as unexpected.
+type Baseline struct {
+	Devices []ExpectedDevice `yaml:"devices" json:"devices"`
+	Ignore  []DeviceMatcher  `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+}
+
+// An ExpectedDevice is a Device the Baseline requires, optionally on a given port and at a given speed.
+// Speed is in Mbps ("480") or a USB speed name ("high").
+type ExpectedDevice struct {
+	Name  string        `yaml:"name" json:"name"`
+	Match DeviceMatcher `yaml:"match" json:"match"`
+	Port  string        `yaml:"port,omitempty" json:"port,omitempty"`
+	Speed string        `yaml:"speed,omitempty" json:"speed,omitempty"`
+}
+
+// A DeviationKind describes how the connected Devices differ from the Baseline.
+type DeviationKind string
+
+// These constants represent the kinds of Deviation.
+const (
+	DeviationMissing    DeviationKind = "missing"
+	DeviationUnexpected DeviationKind = "unexpected"
+	DeviationWrongPort  DeviationKind = "wrong-port"
+	DeviationWrongSpeed DeviationKind = "wrong-speed"
+)
+
+// A Deviation is one difference between the Baseline and the connected Devices. Expected is nil for
+// unexpected Devices and Device is nil for missing ones.
+type Deviation struct {
+	Kind     DeviationKind   `json:"kind"`
+	Expected *ExpectedDevice `json:"expected,omitempty"`
+	Device   *Device         `json:"device,omitempty"`
+	Message  string          `json:"message"`
+}
+
+// A BaselineResult pairs an ExpectedDevice with the Device found for it, if any.
+type BaselineResult struct {
+	Expected   ExpectedDevice `json:"expected"`
+	Device     *Device        `json:"device,omitempty"`
+	Deviations []Deviation    `json:"deviations,omitempty"`
+}
+
+// A BaselineReport is the outcome of checking Devices against a Baseline.
+type BaselineReport struct {
+	Results    []BaselineResult `json:"results"`
+	Unexpected []Deviation      `json:"unexpected,omitempty"`
+}
+
+// LoadBaseline reads a Baseline from a YAML file.
+func LoadBaseline(path string) (Baseline, error) {
+	var baseline Baseline
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline, err
+	}
+	if err := yaml.Unmarshal(data, &baseline); err != nil {
+		return Baseline{}, err
+	}
+
+	for i, expected := range baseline.Devices {
+		if expected.Match.IsZero() {
+			return Baseline{}, fmt.Errorf("device %d (%s): match is empty", i+1, expected.Name)
+		}
+		if expected.Speed != "" && speedMbps(expected.Speed) == 0 {
+			return Baseline{}, fmt.Errorf("device %d (%s): invalid speed %q", i+1, expected.Name, expected.Speed)
+		}
+	}
+
+	return baseline, nil
+}
+
+// Check compares the connected Devices with the Baseline. Removed Devices are not considered connected.
+func (b Baseline) Check(devices []Device) BaselineReport {
+	var report BaselineReport
+	var present []Device
+	for _, device := range devices {
+		if device.State != StateRemoved {
+			present = append(present, device)
+		}
+	}
+	claimed := make([]bool, len(present))
+
+	// Claim devices on their expected port first so a misplaced twin does not steal another's slot.
+	found := make([]int, len(b.Devices))
+	for i, expected := range b.Devices {
+		found[i] = -1
+		if expected.Port == "" {
+			continue
+		}
+		for j, device := range present {
+			if !claimed[j] && expected.Match.Matches(device) && matchPort(expected.Port, device.PortPath()) {
+				found[i] = j
+				claimed[j] = true
+				break
+			}
+		}
+	}
+	for i, expected := range b.Devices {
+		if found[i] >= 0 {
+			continue
+		}
+		for j, device := range present {
+			if !claimed[j] && expected.Match.Matches(device) {
+				found[i] = j
+				claimed[j] = true
+				break
+			}
+		}
+	}
+
+	for i, expected := range b.Devices {
+		result := BaselineResult{Expected: expected}
+		if found[i] < 0 {
+			result.Deviations = append(result.Deviations, Deviation{
+				Kind:     DeviationMissing,
+				Expected: &b.Devices[i],
+				Message:  fmt.Sprintf("%s (%s) is missing", expected.Name, expected.Match),
+			})
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		device := present[found[i]]
+		result.Device = &device
+		if expected.Port != "" && !matchPort(expected.Port, device.PortPath()) {
+			result.Deviations = append(result.Deviations, Deviation{
+				Kind:     DeviationWrongPort,
+				Expected: &b.Devices[i],
+				Device:   &device,
+				Message:  fmt.Sprintf("%s is on port %s, expected %s", expected.Name, device.PortPath(), expected.Port),
+			})
+		}
+		if expected.Speed != "" && speedMbps(expected.Speed) != speedMbps(device.Speed) {
+			result.Deviations = append(result.Deviations, Deviation{
+				Kind:     DeviationWrongSpeed,
+				Expected: &b.Devices[i],
+				Device:   &device,
+				Message: fmt.Sprintf("%s runs at %s Mbps, expected %s Mbps", expected.Name,
+					formatMbps(speedMbps(device.Speed)), formatMbps(speedMbps(expected.Speed))),
+			})
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	for j, device := range present {
+		if claimed[j] || len(device.Path) == 0 || b.ignores(device) {
+			continue
+		}
+		report.Unexpected = append(report.Unexpected, Deviation{
+			Kind:    DeviationUnexpected,
+			Device:  &present[j],
+			Message: fmt.Sprintf("unexpected %s (%s:%s) on port %s", device.DisplayName(), device.VendorID, device.ProductID, device.PortPath()),
+		})
+	}
+
+	return report
+}
+
+func (b Baseline) ignores(device Device) bool {
+	for _, matcher := range b.Ignore {
+		if matcher.Matches(device) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Deviations returns every Deviation of the report, in baseline order followed by the unexpected Devices.
+func (r BaselineReport) Deviations() []Deviation {
+	var deviations []Deviation
+	for _, result := range r.Results {
+		deviations = append(deviations, result.Deviations...)
+	}
+
+	return append(deviations, r.Unexpected...)
+}
+
+// OK reports whether the Devices matched the Baseline exactly.
+func (r BaselineReport) OK() bool {
+	return len(r.Deviations()) == 0
+}
+
+// DeviationsByKey groups the Deviations of connected Devices by Device key.
+func (r BaselineReport) DeviationsByKey() map[string][]Deviation {
+	byKey := make(map[string][]Deviation)
+	for _, deviation := range r.Deviations() {
+		if deviation.Device != nil {
+			key := deviation.Device.Key()
+			byKey[key] = append(byKey[key], deviation)
+		}
+	}
+
+	return byKey
+}
+
+// speedNames maps the USB speed names used by libusb to their signaling rate in Mbps.
+var speedNames = map[string]float64{
+	"low":        1.5,
+	"full":       12,
+	"high":       480,
+	"super":      5000,
+	"super+":     10000,
+	"superplus":  10000,
+	"super-plus": 10000,
+}
+
+// speedMbps converts a speed in Mbps, optionally suffixed with "M", or a speed name to Mbps.
+// It returns 0 for unknown speeds.
+func speedMbps(speed string) float64 {
+	speed = strings.ToLower(strings.TrimSpace(speed))
+	if mbps, ok := speedNames[strings.TrimSuffix(speed, " speed")]; ok {
+		return mbps
+	}
+
+	mbps, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(speed, "mbps"), "m"), 64)
+	if err != nil {
+		return 0
+	}
+
+	return mbps
+}
+
+func formatMbps(mbps float64) string {
+	return strconv.FormatFloat(mbps, 'g', -1, 64)
+}
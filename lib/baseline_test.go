@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rigHub = Device{Path: []int{}, Name: "Root hub", VendorID: "1d6b", ProductID: "0002", Speed: "480", Bus: 1, State: StateNormal}
+
+func rigProbe(serial string, port ...int) Device {
+	return Device{Path: port, Name: "J-Link", VendorID: "1366", ProductID: "0105", Speed: "12", Bus: 1, Serial: serial, State: StateNormal}
+}
+
+var rigBaseline = Baseline{Devices: []ExpectedDevice{
+	{Name: "probe A", Match: DeviceMatcher{VendorID: "1366", ProductID: "0105"}, Port: "1-1", Speed: "full"},
+	{Name: "probe B", Match: DeviceMatcher{VendorID: "1366", ProductID: "0105"}, Port: "1-2", Speed: "12"},
+}}
+
+func kinds(deviations []Deviation) []DeviationKind {
+	var result []DeviationKind
+	for _, deviation := range deviations {
+		result = append(result, deviation.Kind)
+	}
+
+	return result
+}
+
+func TestBaselineCheck_OK(t *testing.T) {
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("A", 1), rigProbe("B", 2)})
+	assert.True(t, report.OK(), "unexpected deviations: %v", report.Deviations())
+	assert.Len(t, report.Results, 2)
+	assert.Equal(t, "1-1", report.Results[0].Device.PortPath())
+}
+
+func TestBaselineCheck_Missing(t *testing.T) {
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("B", 2)})
+	assert.Equal(t, []DeviationKind{DeviationMissing}, kinds(report.Deviations()))
+	assert.Equal(t, "probe A", report.Deviations()[0].Expected.Name)
+	assert.Nil(t, report.Results[0].Device)
+}
+
+func TestBaselineCheck_RemovedDevicesAreMissing(t *testing.T) {
+	removed := rigProbe("A", 1)
+	removed.State = StateRemoved
+
+	report := rigBaseline.Check([]Device{rigHub, removed, rigProbe("B", 2)})
+	assert.Equal(t, []DeviationKind{DeviationMissing}, kinds(report.Deviations()))
+}
+
+func TestBaselineCheck_WrongPort(t *testing.T) {
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("A", 1), rigProbe("B", 3)})
+	deviations := report.Deviations()
+	assert.Equal(t, []DeviationKind{DeviationWrongPort}, kinds(deviations))
+	assert.Equal(t, "probe B is on port 1-3, expected 1-2", deviations[0].Message)
+}
+
+func TestBaselineCheck_WrongSpeed(t *testing.T) {
+	slow := rigProbe("B", 2)
+	slow.Speed = "1.5"
+
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("A", 1), slow})
+	deviations := report.Deviations()
+	assert.Equal(t, []DeviationKind{DeviationWrongSpeed}, kinds(deviations))
+	assert.Equal(t, "probe B runs at 1.5 Mbps, expected 12 Mbps", deviations[0].Message)
+}
+
+func TestBaselineCheck_Unexpected(t *testing.T) {
+	keyboard := Device{Path: []int{4}, Name: "Keyboard", VendorID: "046d", ProductID: "c31c", Speed: "1.5", Bus: 1}
+	hub := Device{Path: []int{5}, Name: "Hub", VendorID: "05e3", ProductID: "0610", Speed: "480", Bus: 1}
+	baseline := rigBaseline
+	baseline.Ignore = []DeviceMatcher{{VendorID: "05e3"}}
+
+	report := baseline.Check([]Device{rigHub, rigProbe("A", 1), rigProbe("B", 2), keyboard, hub})
+	deviations := report.Deviations()
+	assert.Equal(t, []DeviationKind{DeviationUnexpected}, kinds(deviations), "root hubs and ignored devices are expected")
+	assert.Equal(t, "046d", deviations[0].Device.VendorID)
+}
+
+func TestBaselineCheck_PrefersExpectedPort(t *testing.T) {
+	// Both probes match both entries; each should be paired with the entry for its own port.
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("B", 2), rigProbe("A", 1)})
+	assert.True(t, report.OK(), "unexpected deviations: %v", report.Deviations())
+}
+
+func TestBaselineReport_DeviationsByKey(t *testing.T) {
+	misplaced := rigProbe("B", 3)
+	report := rigBaseline.Check([]Device{rigHub, rigProbe("A", 1), misplaced})
+
+	byKey := report.DeviationsByKey()
+	assert.Len(t, byKey, 1)
+	assert.Equal(t, DeviationWrongPort, byKey[misplaced.Key()][0].Kind)
+}
+
+func TestSpeedMbps(t *testing.T) {
+	assert.Equal(t, 480.0, speedMbps("480"))
+	assert.Equal(t, 480.0, speedMbps("high"))
+	assert.Equal(t, 480.0, speedMbps("480M"))
+	assert.Equal(t, 5000.0, speedMbps("super"))
+	assert.Equal(t, 1.5, speedMbps("1.5"))
+	assert.Equal(t, 0.0, speedMbps("unknown"))
+}
+
+func TestLoadBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.yaml")
+	data := `
+devices:
+  - name: ST-Link
+    match: {vid: "0483", pid: "374b"}
+    port: 1-2
+    speed: high
+ignore:
+  - {vid: "05e3"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	baseline, err := LoadBaseline(path)
+	require.NoError(t, err)
+	require.Len(t, baseline.Devices, 1)
+	assert.Equal(t, "1-2", baseline.Devices[0].Port)
+	assert.Equal(t, "05e3", baseline.Ignore[0].VendorID)
+
+	require.NoError(t, os.WriteFile(path, []byte("devices:\n  - name: x\n    match: {vid: \"1\"}\n    speed: warp\n"), 0o644))
+	_, err = LoadBaseline(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package lib
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/gousb"
+)
+
+// An Interface represents one interface of a Device's first configuration. Driver is the kernel driver
+// bound to it, where known.
+type Interface struct {
+	Number   int    `json:"number"`
+	Class    string `json:"class"`
+	SubClass int    `json:"subClass"`
+	Protocol int    `json:"protocol"`
+	Driver   string `json:"driver"`
+}
+
+// classNames holds the short, script-friendly names of the USB-IF class codes.
+var classNames = map[gousb.Class]string{
+	gousb.ClassPerInterface:       "per-interface",
+	gousb.ClassAudio:              "audio",
+	gousb.ClassComm:               "comm",
+	gousb.ClassHID:                "hid",
+	gousb.ClassPhysical:           "physical",
+	gousb.ClassImage:              "image",
+	gousb.ClassPrinter:            "printer",
+	gousb.ClassMassStorage:        "storage",
+	gousb.ClassHub:                "hub",
+	gousb.ClassData:               "cdc-data",
+	gousb.ClassSmartCard:          "smart-card",
+	gousb.ClassContentSecurity:    "content-security",
+	gousb.ClassVideo:              "video",
+	gousb.ClassPersonalHealthcare: "healthcare",
+	gousb.ClassAudioVideo:         "audio-video",
+	gousb.ClassBillboard:          "billboard",
+	gousb.ClassUSBTypeCBridge:     "type-c-bridge",
+	gousb.ClassDiagnosticDevice:   "diagnostic",
+	gousb.ClassWireless:           "wireless",
+	gousb.ClassMiscellaneous:      "misc",
+	gousb.ClassApplication:        "app-specific",
+	gousb.ClassVendorSpec:         "vendor-specific",
+}
+
+// className returns the short name of a class code, or its hex value when it has none.
+func className(class gousb.Class) string {
+	if name, ok := classNames[class]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("0x%02x", uint8(class))
+}
+
+// normalizeClass converts a class name or a hex class code, written with two digits such as "03" or with a
+// "0x" prefix such as "0x3", to the short class name. Decimal codes are not accepted: "10" is 0x10.
+func normalizeClass(class string) string {
+	class = strings.ToLower(strings.TrimSpace(class))
+
+	if code, err := strconv.ParseUint(strings.TrimPrefix(class, "0x"), 16, 8); err == nil &&
+		(strings.HasPrefix(class, "0x") || len(class) == 2) {
+		return className(gousb.Class(code))
+	}
+
+	switch class {
+	case "mass-storage", "msc":
+		return "storage"
+	case "cdc", "communications":
+		return "comm"
+	case "vendor":
+		return "vendor-specific"
+	}
+
+	return class
+}
+
+// firstConfig returns the lowest numbered configuration of a device, which is the one usually active.
+func firstConfig(desc gousb.DeviceDesc) (gousb.ConfigDesc, bool) {
+	if len(desc.Configs) == 0 {
+		return gousb.ConfigDesc{}, false
+	}
+
+	numbers := make([]int, 0, len(desc.Configs))
+	for number := range desc.Configs {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	return desc.Configs[numbers[0]], true
+}
+
+// interfacesFromDesc lists the interfaces of the lowest numbered configuration of a device.
+func interfacesFromDesc(desc gousb.DeviceDesc) []Interface {
+	config, ok := firstConfig(desc)
+	if !ok {
+		return nil
+	}
+
+	var interfaces []Interface
+	for _, intf := range config.Interfaces {
+		if len(intf.AltSettings) == 0 {
+			continue
+		}
+		setting := intf.AltSettings[0]
+		interfaces = append(interfaces, Interface{
+			Number:   intf.Number,
+			Class:    className(setting.Class),
+			SubClass: int(setting.SubClass),
+			Protocol: int(setting.Protocol),
+		})
+	}
+
+	return interfaces
+}
+
+// HasClass reports whether the Device or one of its interfaces belongs to the class, given by name or hex code.
+func (d *Device) HasClass(class string) bool {
+	class = normalizeClass(class)
+	if d.Class == class {
+		return true
+	}
+	for _, intf := range d.Interfaces {
+		if intf.Class == class {
+			return true
+		}
+	}
+
+	return false
+}
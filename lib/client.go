@@ -0,0 +1,340 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clientRetryDelay     = 2 * time.Second     // how long a Client waits before reconnecting to a daemon
+	clientRequestTimeout = 10 * time.Second    // bounds requests other than the event stream
+	clientStaleAfter     = 3 * streamKeepAlive // silence after which a connection is considered stale
+	clientMaxMessage     = 16 << 20            // bounds the size of one message of the event stream
+)
+
+// A ConnectionState describes the connection of a Client to its daemon.
+type ConnectionState string
+
+// These constants represent the ConnectionState of a Client.
+const (
+	ConnectionConnecting ConnectionState = "connecting"
+	ConnectionConnected  ConnectionState = "connected"
+	ConnectionStale      ConnectionState = "stale" // connected, but the daemon has not been heard from for a while
+	ConnectionError      ConnectionState = "error"
+)
+
+// A Client is a Source following the Devices of a usb-tree daemon started with `usb-tree serve`. It
+// reconnects whenever the connection is lost.
+type Client struct {
+	address    string
+	baseURL    string
+	httpClient *http.Client
+
+	lock        sync.Mutex
+	log         []Log
+	cancel      context.CancelFunc
+	hostname    string
+	state       ConnectionState
+	lastMessage time.Time
+	err         error // why the connection was last lost
+	failures    int   // connections lost in a row
+	lastSeq     int   // Seq of the newest log entry received from the daemon
+}
+
+// Connect returns a Client for the daemon at address, which takes the same forms as for Listen: a TCP host
+// and port, optionally as an http:// URL, or "unix:" followed by a socket path. It does not connect until
+// Start is called.
+func Connect(address string) *Client {
+	client := &Client{address: address, baseURL: address, httpClient: &http.Client{}, state: ConnectionConnecting}
+
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		var dialer net.Dialer
+		client.baseURL = "http://usb-tree"
+		client.httpClient.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
+			},
+		}
+	} else if !strings.Contains(address, "://") {
+		client.baseURL = "http://" + address
+	}
+	client.baseURL = strings.TrimSuffix(client.baseURL, "/")
+
+	return client
+}
+
+// Start connects to the daemon and calls onUpdate with its Devices whenever they change, and with nil while
+// the daemon cannot be reached. It connects in the background, so it never fails; Health reports whether the
+// daemon is reached.
+func (c *Client) Start(onUpdate func([]Device)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.lock.Lock()
+	c.cancel = cancel
+	c.lock.Unlock()
+
+	go func() {
+		for {
+			err := c.follow(ctx, onUpdate)
+			if ctx.Err() != nil {
+				return
+			}
+			c.lock.Lock()
+			c.state = ConnectionError
+			c.err = err
+			c.failures++
+			c.lock.Unlock()
+			getLogger().Warn("connection to daemon lost", "address", c.address, "error", err)
+			c.addLog(Log{Time: time.Now(), Text: fmt.Sprintf("Connection to %s lost: %v", c.address, err), State: StateError})
+			onUpdate(nil)
+
+			select {
+			case <-time.After(clientRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop disconnects from the daemon.
+func (c *Client) Stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+// Refresh asks the daemon to read its Devices again.
+func (c *Client) Refresh() time.Time {
+	refreshTime := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clientRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/refresh", nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = c.httpClient.Do(req); err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				err = fmt.Errorf("%s", resp.Status)
+			}
+		}
+	}
+	if err != nil {
+		c.addLog(Log{Time: refreshTime, Text: fmt.Sprintf("Refresh of %s failed: %v", c.address, err), State: StateError})
+	}
+
+	return refreshTime
+}
+
+// Host returns the name of the machine the daemon runs on, or its address until it is known.
+func (c *Client) Host() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.hostname == "" {
+		return c.address
+	}
+
+	return c.hostname
+}
+
+// State returns the ConnectionState of the Client.
+func (c *Client) State() ConnectionState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.state == ConnectionConnected && time.Since(c.lastMessage) > clientStaleAfter {
+		return ConnectionStale
+	}
+
+	return c.state
+}
+
+// Health describes the connection to the daemon: ok while connected, failing while it cannot be reached or
+// is stale.
+func (c *Client) Health() HealthStatus {
+	state := c.State()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	status := HealthStatus{LastPoll: c.lastMessage, Failures: c.failures}
+	switch {
+	case c.cancel == nil:
+		status.State = HealthStopped
+	case state == ConnectionConnecting:
+		status.State = HealthStarting
+	case state == ConnectionConnected:
+		status.State = HealthOK
+	case state == ConnectionStale:
+		status.State = HealthFailing
+		status.Err = fmt.Errorf("no message from %s since %s", c.address, c.lastMessage.Format(time.TimeOnly))
+	default:
+		status.State = HealthFailing
+		status.Err = c.err
+	}
+	if status.Err != nil {
+		status.Error = status.Err.Error()
+	}
+
+	return status
+}
+
+// Log returns the log of the daemon, with the connection errors of the Client.
+func (c *Client) Log() []Log {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return slices.Clone(c.log)
+}
+
+func (c *Client) addLog(log Log) {
+	c.lock.Lock()
+	c.log = append(c.log, log)
+	c.lock.Unlock()
+}
+
+// follow reads the event stream of the daemon until it ends. The log entries missed while disconnected are
+// fetched once the stream is open, so none are lost in between.
+func (c *Client) follow(ctx context.Context, onUpdate func([]Device)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+
+	if err := c.fetchLog(ctx); err != nil {
+		return err
+	}
+	c.heard()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, clientMaxMessage)
+	var event, data string
+	for scanner.Scan() {
+		c.heard()
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "event: "); ok {
+			event = value
+		} else if value, ok := strings.CutPrefix(line, "data: "); ok {
+			data = value
+		} else if line == "" {
+			if err := c.handleMessage(event, data, onUpdate); err != nil {
+				return err
+			}
+			event, data = "", ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("the daemon closed the event stream")
+}
+
+// heard records that the daemon is alive.
+func (c *Client) heard() {
+	c.lock.Lock()
+	c.state = ConnectionConnected
+	c.lastMessage = time.Now()
+	c.failures = 0
+	c.lock.Unlock()
+}
+
+// fetchLog appends the log entries of the daemon that were not received yet, asking for those after the
+// Seq of the newest one received.
+func (c *Client) fetchLog(ctx context.Context) error {
+	c.lock.Lock()
+	lastSeq := c.lastSeq
+	c.lock.Unlock()
+
+	query := ""
+	if lastSeq > 0 {
+		query = "?after=" + strconv.Itoa(lastSeq)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/logs"+query, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("reading log: %s", resp.Status)
+	}
+
+	var logs []Log
+	if err := json.NewDecoder(resp.Body).Decode(&logs); err != nil {
+		return fmt.Errorf("reading log: %w", err)
+	}
+	if len(logs) > 0 {
+		c.lock.Lock()
+		c.log = append(c.log, logs...)
+		// A restarted daemon numbers its log from 1 again and returns all of it.
+		c.lastSeq = logs[len(logs)-1].Seq
+		c.lock.Unlock()
+	}
+
+	return nil
+}
+
+// handleMessage applies one message of the event stream. Log entries already fetched are skipped by their Seq.
+func (c *Client) handleMessage(event, data string, onUpdate func([]Device)) error {
+	switch event {
+	case "host":
+		var host struct {
+			Hostname string `json:"hostname"`
+		}
+		if err := json.Unmarshal([]byte(data), &host); err != nil {
+			return fmt.Errorf("reading host: %w", err)
+		}
+		c.lock.Lock()
+		c.hostname = host.Hostname
+		c.lock.Unlock()
+
+	case "devices":
+		var devices []Device
+		if err := json.Unmarshal([]byte(data), &devices); err != nil {
+			return fmt.Errorf("reading devices: %w", err)
+		}
+		onUpdate(devices)
+
+	case "log":
+		var log Log
+		if err := json.Unmarshal([]byte(data), &log); err != nil {
+			return fmt.Errorf("reading log: %w", err)
+		}
+		c.lock.Lock()
+		if log.Seq > c.lastSeq {
+			c.log = append(c.log, log)
+			c.lastSeq = log.Seq
+		}
+		c.lock.Unlock()
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextUpdate waits for the next Devices passed to an onUpdate callback.
+func nextUpdate(t *testing.T, updates <-chan []Device) []Device {
+	t.Helper()
+	select {
+	case devices := <-updates:
+		return devices
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no update received")
+		return nil
+	}
+}
+
+func deviceNames(devices []Device) []string {
+	var names []string
+	for _, device := range devices {
+		names = append(names, device.Name)
+	}
+
+	return names
+}
+
+func TestClient_FollowsServer(t *testing.T) {
+	logs = nil
+	addErrorLog("before connecting", time.Now(), StateError)
+	server := NewServer()
+	server.update([]Device{device4})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	updates := make(chan []Device, 10)
+	client := Connect(httpServer.URL)
+	client.Start(func(devices []Device) { updates <- devices })
+	defer client.Stop()
+
+	assert.Equal(t, []string{"Root"}, deviceNames(nextUpdate(t, updates)))
+	require.Len(t, client.Log(), 1)
+	assert.Equal(t, "before connecting", client.Log()[0].Text)
+
+	addErrorLog("after connecting", time.Now(), StateError)
+	server.update([]Device{device4, probe})
+	assert.Equal(t, []string{"Root", "SEGGER J-Link"}, deviceNames(nextUpdate(t, updates)))
+	require.Len(t, client.Log(), 2)
+	assert.Equal(t, "after connecting", client.Log()[1].Text)
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	server := NewServer()
+	server.update([]Device{device4, probe})
+	listener, err := Listen("unix:" + filepath.Join(t.TempDir(), "usb-tree.sock"))
+	require.NoError(t, err)
+	httpServer := &http.Server{Handler: server}
+	go func() { _ = httpServer.Serve(listener) }()
+	defer httpServer.Close()
+
+	updates := make(chan []Device, 10)
+	client := Connect("unix:" + listener.Addr().String())
+	client.Start(func(devices []Device) { updates <- devices })
+	defer client.Stop()
+
+	assert.Len(t, nextUpdate(t, updates), 2)
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	updates := make(chan []Device, 10)
+	client := Connect("unix:" + filepath.Join(t.TempDir(), "missing.sock"))
+	client.Start(func(devices []Device) { updates <- devices })
+	defer client.Stop()
+
+	assert.Nil(t, nextUpdate(t, updates))
+	require.NotEmpty(t, client.Log())
+	assert.Equal(t, StateError, client.Log()[0].State)
+}
+
+func TestClient_FetchLogAfterPartialPoll(t *testing.T) {
+	logs = nil
+	pollTime := time.Now()
+	addErrorLog("Keyboard added", pollTime, StateAdded)
+	addErrorLog("Keyboard added", pollTime, StateAdded)
+	addErrorLog("Mouse added", pollTime, StateAdded)
+	httpServer := httptest.NewServer(NewServer())
+	defer httpServer.Close()
+
+	client := Connect(httpServer.URL)
+	first := GetLog()[0]
+	first.Seq = 1
+	client.log = []Log{first, {Time: pollTime.Add(time.Second), Text: "Connection lost", State: StateError}}
+	client.lastSeq = 1
+	require.NoError(t, client.fetchLog(context.Background()))
+
+	var texts []string
+	for _, log := range client.Log() {
+		texts = append(texts, log.Text)
+	}
+	assert.Equal(t, []string{"Keyboard added", "Connection lost", "Keyboard added", "Mouse added"}, texts,
+		"the rest of the poll should be fetched once, identical entries included")
+
+	entry := `{"Text":"Mouse added","State":"added","Time":"` + pollTime.Format(time.RFC3339Nano) + `","Seq":3}`
+	require.NoError(t, client.handleMessage("log", entry, nil))
+	assert.Len(t, client.Log(), 4, "an entry both fetched and streamed should be added once")
+
+	entry = `{"Text":"Mouse added","State":"added","Time":"` + pollTime.Format(time.RFC3339Nano) + `","Seq":4}`
+	require.NoError(t, client.handleMessage("log", entry, nil))
+	assert.Len(t, client.Log(), 5, "a later entry identical to a fetched one should be added")
+
+	client.lastSeq = 10
+	require.NoError(t, client.fetchLog(context.Background()))
+	assert.Len(t, client.Log(), 8, "the whole log of a restarted daemon should be fetched")
+	assert.Equal(t, 3, client.lastSeq)
+}
@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the user settings read from the usb-tree configuration file.
+type Config struct {
+	Aliases    []Alias     `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	PortLabels []PortLabel `yaml:"ports,omitempty" json:"ports,omitempty"`
+	Rules      []Rule      `yaml:"rules,omitempty" json:"rules,omitempty"`
+
+	// Allow lists the Devices permitted by the security policy. When empty, only suspicious composite
+	// Devices are flagged.
+	Allow []DeviceMatcher `yaml:"allow,omitempty" json:"allow,omitempty"`
+
+	// Enrichers switches the Enrichers off by name, e.g. "sysfs-power: false"; those not listed are on.
+	Enrichers map[string]bool `yaml:"enrichers,omitempty" json:"enrichers,omitempty"`
+
+	// Polling is the timing of the monitoring, applied with SetPolling by the programs reading the file.
+	Polling Polling `yaml:"polling,omitempty" json:"polling,omitzero"`
+}
+
+var (
+	config     Config
+	configLock sync.RWMutex
+)
+
+// DefaultConfigPath returns the location of the configuration file in the user's config directory.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "usb-tree.yaml"
+	}
+
+	return filepath.Join(dir, "usb-tree", "config.yaml")
+}
+
+// LoadConfig reads a Config from path. A missing file results in an empty Config.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks the rules, the enrichers and the polling of the Config.
+func (c Config) Validate() error {
+	if err := validateEnrichers(c.Enrichers); err != nil {
+		return err
+	}
+	if err := c.Polling.Validate(); err != nil {
+		return err
+	}
+	for _, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveConfig writes cfg to path, creating the parent directory if needed.
+func SaveConfig(path string, cfg Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// SetConfig replaces the Config applied to Devices on every poll.
+func SetConfig(cfg Config) {
+	configLock.Lock()
+	config = cfg
+	configLock.Unlock()
+}
+
+// GetConfig returns the Config currently applied to Devices.
+func GetConfig() Config {
+	configLock.RLock()
+	defer configLock.RUnlock()
+
+	return config
+}
@@ -0,0 +1,54 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Missing(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Aliases)
+}
+
+func TestSaveAndLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usb-tree", "config.yaml")
+	cfg := Config{Aliases: []Alias{{Match: DeviceMatcher{VendorID: "1366", Serial: "000123"}, Alias: "J-Link #3", Color: "#ff8800"}}}
+
+	require.NoError(t, SaveConfig(path, cfg))
+	loaded, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+aliases:
+  - match: {vid: "1366", pid: "0105", serial: "000123"}
+    alias: J-Link #3
+    note: Bench A, left side
+  - match: {port: "1-4"}
+    alias: Custom board
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Len(t, cfg.Aliases, 2)
+	assert.Equal(t, "000123", cfg.Aliases[0].Match.Serial)
+	assert.Equal(t, "Bench A, left side", cfg.Aliases[0].Note)
+	assert.Equal(t, "1-4", cfg.Aliases[1].Match.Port)
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("aliases: {"), 0o644))
+
+	_, err := LoadConfig(path)
+	assert.Error(t, err)
+}
@@ -3,75 +3,226 @@
 package lib
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jochenvg/go-udev"
 )
 
+// deviceInfo holds the details udev has about a USB device. VendorID, ProductID and DevNum tell whether the
+// device on a port is still the one the details were read for.
 type deviceInfo struct {
-	Name  string
-	Speed string
+	VendorID  string
+	ProductID string
+	DevNum    int
+
+	Name        string
+	VendorName  string
+	ProductName string
+	Speed       string
+	Serial      string
+	DevNode     string
+	Driver      string
+	Ports       int
+	Controller  string
+
+	InterfaceDrivers map[int]string
 }
 
 var (
+	// deviceInfoCache holds the details of the USB devices by sysfs device path, e.g. "1-3.2" or "usb1".
 	deviceInfoCache     = map[string]deviceInfo{}
 	deviceInfoCacheLock sync.RWMutex
+
+	// Lookups in deviceInfoCache since the last takeDeviceInfoCacheStats.
+	deviceInfoCacheHits   atomic.Int64
+	deviceInfoCacheMisses atomic.Int64
+
+	// enumerateUdev reads the details of every USB device from udev; benchmarks replace it.
+	enumerateUdev = udevDeviceInfos
 )
 
-func (d *Device) enrich() bool {
-	info, ok := getPriorityInfo(*d)
+// platformEnrichers returns the Enrichers reading the details Linux has about the Devices.
+func platformEnrichers() []Enricher {
+	return []Enricher{udevEnricher{}, sysfsPowerEnricher{}, driversEnricher{}}
+}
+
+// udevEnumerated tells whether udev was enumerated in the current poll, so it is not again for other cache misses.
+var udevEnumerated atomic.Bool
+
+// udevEnricher adds the details udev has about the Devices, and skips those it does not know yet.
+type udevEnricher struct{}
+
+func (udevEnricher) Name() string {
+	return "udev"
+}
+
+func (udevEnricher) startPoll() {
+	udevEnumerated.Store(false)
+}
+
+func (udevEnricher) Enrich(d *Device) error {
+	info, ok, err := getPriorityInfo(*d)
+	if err != nil {
+		return &PollError{Kind: ErrUdevFailure, Err: err}
+	}
 	if !ok {
-		return false
+		return ErrSkipDevice
 	}
 
-	if len(strings.TrimSpace(info.Name)) > 0 {
+	if strings.TrimSpace(d.Name) == "" && len(strings.TrimSpace(info.Name)) > 0 {
 		d.Name = info.Name
 	}
+	if d.VendorName == "" {
+		d.VendorName = info.VendorName
+	}
+	if d.ProductName == "" {
+		d.ProductName = info.ProductName
+	}
+	// The speed in sysfs replaces the one libusb reports.
 	d.Speed = info.Speed
-	return true
+	if d.Serial == "" {
+		d.Serial = info.Serial
+	}
+	if d.DevNode == "" {
+		d.DevNode = info.DevNode
+	}
+	if d.Ports == 0 {
+		d.Ports = info.Ports
+	}
+	if d.Controller == "" {
+		d.Controller = info.Controller
+	}
+	return nil
 }
 
-func (d *Device) getPriorityNameCacheKey() string {
-	return fmt.Sprintf("%s:%s:%03d:%03d", d.VendorID, d.ProductID, d.Bus, d.DevNum)
+// driversEnricher adds the kernel drivers of the Devices and their interfaces, as udev knows them.
+type driversEnricher struct{}
+
+func (driversEnricher) Name() string {
+	return "drivers"
 }
 
-func getPriorityInfo(device Device) (deviceInfo, bool) {
-	key := device.getPriorityNameCacheKey()
+func (driversEnricher) startPoll() {
+	udevEnumerated.Store(false)
+}
 
-	deviceInfoCacheLock.RLock()
-	_, found := deviceInfoCache[key]
-	deviceInfoCacheLock.RUnlock()
+func (driversEnricher) Enrich(d *Device) error {
+	info, ok, err := getPriorityInfo(*d)
+	if err != nil {
+		return &PollError{Kind: ErrUdevFailure, Err: err}
+	}
+	if !ok {
+		return nil
+	}
 
-	if !found {
-		enumerateAndCache()
+	if d.Driver == "" {
+		d.Driver = info.Driver
 	}
+	for i := range d.Interfaces {
+		if d.Interfaces[i].Driver == "" {
+			d.Interfaces[i].Driver = info.InterfaceDrivers[d.Interfaces[i].Number]
+		}
+	}
+	return nil
+}
+
+// describes reports whether info was read for the Device rather than for another one on the same port.
+func (info deviceInfo) describes(device Device) bool {
+	return info.VendorID == device.VendorID && info.ProductID == device.ProductID && info.DevNum == device.DevNum
+}
 
+// cachedDeviceInfo looks the Device up in deviceInfoCache.
+func cachedDeviceInfo(device Device) (deviceInfo, bool) {
 	deviceInfoCacheLock.RLock()
-	info, found := deviceInfoCache[key]
+	info, found := deviceInfoCache[device.PortPath()]
 	deviceInfoCacheLock.RUnlock()
 
-	if found {
-		return info, true
+	return info, found && info.describes(device)
+}
+
+// getPriorityInfo returns the details udev has about the Device. On a cache miss udev is enumerated, unless
+// that was already done in the poll, so that a poll enumerates it at most once however many Devices are new.
+func getPriorityInfo(device Device) (deviceInfo, bool, error) {
+	if info, found := cachedDeviceInfo(device); found {
+		deviceInfoCacheHits.Add(1)
+		return info, true, nil
+	}
+
+	deviceInfoCacheMisses.Add(1)
+	getLogger().Debug("device info cache miss", "port", device.PortPath(), "device", device.Key())
+	if udevEnumerated.CompareAndSwap(false, true) {
+		if err := enumerateAndCache(); err != nil {
+			return deviceInfo{}, false, err
+		}
+		if info, found := cachedDeviceInfo(device); found {
+			return info, true, nil
+		}
 	}
+	getLogger().Debug("udev does not know the device", "port", device.PortPath(), "device", device.Key())
 
-	return deviceInfo{}, false
+	return deviceInfo{}, false, nil
 }
 
-func enumerateAndCache() {
+// takeDeviceInfoCacheStats returns the number of hits and misses of deviceInfoCache since the last call.
+func takeDeviceInfoCacheStats() (hits, misses int64) {
+	return deviceInfoCacheHits.Swap(0), deviceInfoCacheMisses.Swap(0)
+}
+
+// enumerateAndCache replaces deviceInfoCache with the details udev has about every USB device. The cache is
+// kept when udev cannot be read.
+func enumerateAndCache() error {
+	start := time.Now()
+	newCache, err := enumerateUdev()
+	if err != nil {
+		getLogger().Error("udev enumeration failed", "error", err)
+		return err
+	}
+
+	// Replace the entire cache with write lock (minimize lock time)
+	deviceInfoCacheLock.Lock()
+	deviceInfoCache = newCache
+	deviceInfoCacheLock.Unlock()
+
+	getLogger().Debug("enumerated udev devices", "devices", len(newCache), "duration", time.Since(start))
+
+	return nil
+}
+
+// udevDeviceInfos reads the details udev has about every USB device, by sysfs device path.
+func udevDeviceInfos() (map[string]deviceInfo, error) {
 	u := udev.Udev{}
 	e := u.NewEnumerate()
-	err := e.AddMatchSubsystem("usb")
-	if err != nil {
-		return
+	if err := e.AddMatchSubsystem("usb"); err != nil {
+		return nil, err
 	}
 
-	devices, _ := e.Devices()
+	devices, err := e.Devices()
+	if err != nil {
+		return nil, err
+	}
 
-	newCache := map[string]deviceInfo{}
+	infos := map[string]deviceInfo{}
+	interfaceDrivers := map[string]map[int]string{}
 
 	for _, device := range devices {
+		if device.Devtype() == "usb_interface" {
+			parent := device.Parent()
+			number, err := strconv.ParseInt(device.SysattrValue("bInterfaceNumber"), 16, 0)
+			if parent == nil || err != nil {
+				continue
+			}
+			key := parent.Sysname()
+			if interfaceDrivers[key] == nil {
+				interfaceDrivers[key] = map[int]string{}
+			}
+			interfaceDrivers[key][int(number)] = device.Driver()
+			continue
+		}
+
 		vid := device.PropertyValue("ID_VENDOR_ID")
 
 		if vid != "" {
@@ -86,30 +237,60 @@ func enumerateAndCache() {
 			}
 
 			name := vendorName + " " + deviceName
-			pid := device.PropertyValue("ID_MODEL_ID")
-			bus := device.PropertyValue("BUSNUM")
-			devNum := device.PropertyValue("DEVNUM")
 			speed := device.SysattrValue("speed")
+			serial := device.SysattrValue("serial")
+			ports, _ := strconv.Atoi(device.SysattrValue("maxchild"))
+			devNum, _ := strconv.Atoi(device.PropertyValue("DEVNUM"))
 
-			key := fmt.Sprintf("%s:%s:%03s:%03s", vid, pid, bus, devNum)
+			// Root hubs hang off the host controller, whose driver is the interesting one.
+			driver := device.Driver()
+			controller := hostController(device)
+			if parent := device.Parent(); parent != nil && parent.Subsystem() != "usb" {
+				driver = parent.Driver()
+			}
 
-			newCache[key] = deviceInfo{
-				Name:  name,
-				Speed: speed,
+			infos[device.Sysname()] = deviceInfo{
+				VendorID:    vid,
+				ProductID:   device.PropertyValue("ID_MODEL_ID"),
+				DevNum:      devNum,
+				Name:        name,
+				VendorName:  strings.TrimSpace(vendorName),
+				ProductName: strings.TrimSpace(deviceName),
+				Speed:       speed,
+				Serial:      strings.TrimSpace(serial),
+				DevNode:     device.PropertyValue("DEVNAME"),
+				Driver:      driver,
+				Ports:       ports,
+				Controller:  controller,
 			}
 		}
 	}
 
-	// Replace the entire cache with write lock (minimize lock time)
-	deviceInfoCacheLock.Lock()
-	deviceInfoCache = newCache
-	deviceInfoCacheLock.Unlock()
+	for key, drivers := range interfaceDrivers {
+		if info, ok := infos[key]; ok {
+			info.InterfaceDrivers = drivers
+			infos[key] = info
+		}
+	}
+
+	return infos, nil
 }
 
-func clearPriorityNameCache(device Device) {
-	key := device.getPriorityNameCacheKey()
+// hostController returns the name of the host controller a USB device is attached to, e.g. "0000:00:14.0".
+func hostController(device *udev.Device) string {
+	for parent := device.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Subsystem() != "usb" {
+			return parent.Sysname()
+		}
+	}
 
+	return ""
+}
+
+func clearPriorityNameCache(device Device) {
 	deviceInfoCacheLock.Lock()
-	delete(deviceInfoCache, key)
+	if info, found := deviceInfoCache[device.PortPath()]; found && info.describes(device) {
+		delete(deviceInfoCache, device.PortPath())
+	}
 	deviceInfoCacheLock.Unlock()
 }
@@ -7,6 +7,9 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var testDevices = []Device{
@@ -33,7 +36,7 @@ func TestConcurrentEnrichAndClear(t *testing.T) {
 				time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
 				for _, dev := range testDevices {
 					d := dev
-					_ = d.enrich()
+					_ = udevEnricher{}.Enrich(&d)
 				}
 			}
 		}()
@@ -54,3 +57,152 @@ func TestConcurrentEnrichAndClear(t *testing.T) {
 
 	wg.Wait()
 }
+
+// fakeUdev makes udev know the devices, and returns the number of times it is enumerated.
+func fakeUdev(tb testing.TB, devices ...Device) *int {
+	enumerations := 0
+	enumerateUdev = func() (map[string]deviceInfo, error) {
+		enumerations++
+		infos := make(map[string]deviceInfo, len(devices))
+		for _, device := range devices {
+			infos[device.PortPath()] = deviceInfo{
+				VendorID:  device.VendorID,
+				ProductID: device.ProductID,
+				DevNum:    device.DevNum,
+				Name:      "Vendor " + device.PortPath(),
+				Speed:     device.Speed,
+			}
+		}
+		return infos, nil
+	}
+	deviceInfoCache = map[string]deviceInfo{}
+	tb.Cleanup(func() {
+		enumerateUdev = udevDeviceInfos
+		deviceInfoCache = map[string]deviceInfo{}
+	})
+
+	return &enumerations
+}
+
+// hubTree returns a root hub on bus 1 with a hub on port 1, and n devices on the ports of the hub.
+func hubTree(n int) []Device {
+	devices := []Device{
+		{Bus: 1, VendorID: "1d6b", ProductID: "0002", Speed: "480M", DevNum: 1},
+		{Bus: 1, Path: []int{1}, VendorID: "05e3", ProductID: "0610", Speed: "480M", DevNum: 2},
+	}
+	for port := 1; port <= n; port++ {
+		devices = append(devices, Device{Bus: 1, Path: []int{1, port}, VendorID: "046d", ProductID: "c31c", Speed: "12M", DevNum: 2 + port})
+	}
+
+	return devices
+}
+
+// enrichAll enriches the devices with udev in one poll, as getDevices does, and returns the number of them udev
+// knows.
+func enrichAll(devices []Device) int {
+	udevEnricher{}.startPoll()
+	known := 0
+	for _, device := range devices {
+		if err := (udevEnricher{}).Enrich(&device); err == nil {
+			known++
+		}
+	}
+
+	return known
+}
+
+func TestEnrich_EnumeratesUdevOncePerPoll(t *testing.T) {
+	devices := hubTree(7)
+	enumerations := fakeUdev(t, devices[:5]...)
+
+	assert.Equal(t, 5, enrichAll(devices))
+	assert.Equal(t, 1, *enumerations, "several misses in a poll enumerate udev once")
+	assert.Equal(t, 5, enrichAll(devices[:5]))
+	assert.Equal(t, 1, *enumerations, "known devices are cached")
+
+	replugged := devices[2]
+	replugged.DevNum = 42
+	udevEnricher{}.startPoll()
+	err := udevEnricher{}.Enrich(&replugged)
+	assert.ErrorIs(t, err, ErrSkipDevice, "details of the device formerly on the port are not reused")
+	assert.Equal(t, 2, *enumerations)
+}
+
+func TestEnrich_KeyedByPortPath(t *testing.T) {
+	devices := hubTree(1)
+	fakeUdev(t, devices...)
+
+	device := devices[2]
+	udevEnricher{}.startPoll()
+	require.NoError(t, udevEnricher{}.Enrich(&device))
+	assert.Equal(t, "Vendor 1-1.1", device.Name)
+	assert.Contains(t, deviceInfoCache, "1-1.1")
+	assert.Contains(t, deviceInfoCache, "usb1")
+
+	clearPriorityNameCache(device)
+	assert.NotContains(t, deviceInfoCache, "1-1.1")
+}
+
+// BenchmarkEnrich_Idle polls devices that did not change, which needs no udev enumeration.
+func BenchmarkEnrich_Idle(b *testing.B) {
+	devices := hubTree(24)
+	enumerations := fakeUdev(b, devices...)
+	enrichAll(devices)
+	*enumerations = 0
+
+	b.ReportAllocs()
+	for b.Loop() {
+		enrichAll(devices)
+	}
+	b.ReportMetric(float64(*enumerations)/float64(b.N), "enumerations/op")
+}
+
+// BenchmarkEnrich_HubBurst polls right after a hub with 7 devices was plugged in, 3 of which udev has not
+// processed yet: udev is enumerated once rather than for every unknown device.
+func BenchmarkEnrich_HubBurst(b *testing.B) {
+	idle := hubTree(0)
+	burst := append(hubTree(7), Device{Bus: 1, Path: []int{2}, VendorID: "05e3", ProductID: "0610", Speed: "480M", DevNum: 20})
+	enumerations := fakeUdev(b, burst[:len(burst)-3]...)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		b.StopTimer()
+		deviceInfoCache = map[string]deviceInfo{}
+		enrichAll(idle)
+		*enumerations = 0
+		b.StartTimer()
+
+		enrichAll(burst)
+	}
+	b.ReportMetric(float64(*enumerations), "enumerations/op")
+}
+
+// BenchmarkGetDevices_Idle polls the devices of the machine with the libusb context kept open between polls.
+func BenchmarkGetDevices_Idle(b *testing.B) {
+	if _, _, err := getDevices(); err != nil {
+		b.Skipf("cannot read USB devices: %v", err)
+	}
+	b.Cleanup(Stop)
+
+	for b.Loop() {
+		_, _, _ = getDevices()
+	}
+}
+
+// BenchmarkNewUSBContext measures the cost of opening a libusb context, which getDevices no longer pays on
+// every poll.
+func BenchmarkNewUSBContext(b *testing.B) {
+	ctx, err := newUSBContext()
+	if err != nil {
+		b.Skipf("libusb is unavailable: %v", err)
+	}
+	_ = ctx.Close()
+
+	for b.Loop() {
+		ctx, err := newUSBContext()
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = ctx.Close()
+	}
+}
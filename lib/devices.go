@@ -2,7 +2,12 @@ package lib
 
 import (
 	"fmt"
+	"log/slog"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/gousb"
@@ -14,14 +19,40 @@ type LogState string
 
 // A Device represents a USB Device
 type Device struct {
-	Path      []int    `json:"path"`
-	Name      string   `json:"name"`
-	VendorID  string   `json:"vendorId"`
-	ProductID string   `json:"productId"`
-	Speed     string   `json:"speed"`
-	Bus       int      `json:"bus"`
-	State     LogState `json:"state"`
-	DevNum    int      `json:"devNum"`
+	Path       []int    `json:"path"`
+	Name       string   `json:"name"`
+	VendorID   string   `json:"vendorId"`
+	ProductID  string   `json:"productId"`
+	Speed      string   `json:"speed"`
+	Bus        int      `json:"bus"`
+	State      LogState `json:"state"`
+	DevNum     int      `json:"devNum"`
+	Serial     string   `json:"serial"`
+	Alias      string   `json:"alias"`
+	Note       string   `json:"note"`
+	Color      string   `json:"color"`
+	PortLabel  string   `json:"portLabel"`
+	Class      string   `json:"class"`
+	DevNode    string   `json:"devNode"`
+	Driver     string   `json:"driver"`     // kernel driver of the device; the host controller driver for root hubs
+	Ports      int      `json:"ports"`      // number of downstream ports of hubs
+	Controller string   `json:"controller"` // host controller the device is attached to, e.g. "0000:00:14.0"
+
+	VendorName  string `json:"vendorName"`  // vendor part of Name, e.g. "Logitech, Inc."
+	ProductName string `json:"productName"` // product part of Name, e.g. "Keyboard K120"
+	Firmware    string `json:"firmware"`    // release number of the device (bcdDevice), e.g. "1.10"
+	USBVersion  string `json:"usbVersion"`  // USB specification release the device supports, e.g. "3.20"
+	MaxPower    int    `json:"maxPower"`    // most current the device draws from the bus, in mA
+	SelfPowered bool   `json:"selfPowered"` // whether the device has its own power supply
+	PowerState  string `json:"powerState"`  // runtime power management state, e.g. "active" or "suspended"
+	Autosuspend bool   `json:"autosuspend"` // whether the kernel suspends the device when it is idle
+
+	Interfaces []Interface `json:"interfaces"`
+	Violations []Violation `json:"violations"`
+
+	// Host names the machine the Device is connected to when Devices of several machines are combined by
+	// a MultiSource; it is empty for the Devices of a single machine.
+	Host string `json:"host,omitempty"`
 }
 
 // TreeNode represents a Device and its children for building tree structures.
@@ -32,10 +63,13 @@ type TreeNode struct {
 
 // Log represents a change in a Device.
 type Log struct {
-	Time  time.Time
-	Text  string
-	Speed string
-	State LogState
+	Time      time.Time
+	Text      string
+	Speed     string
+	State     LogState
+	PortLabel string
+	Host      string `json:",omitempty"` // machine the entry comes from when a MultiSource combines several
+	Seq       int    `json:",omitempty"` // position of the entry in the log of a daemon, starting at 1, set by a Server
 }
 
 // These constants represent the State of a Device.
@@ -44,126 +78,455 @@ const (
 	StateAdded   LogState = "added"
 	StateRemoved LogState = "removed"
 	StateError   LogState = "error"
+
+	// StateViolation marks log entries about Devices breaking the security policy.
+	StateViolation LogState = "violation"
 )
 
 var (
 	cachedDevices []Device
 	lastMergedMap map[string]Device
 	logs          []Log
+	logsLock      sync.Mutex
+)
+
+// The timing of the polls made by Init. Tests shorten them.
+var (
+	pollInterval  = DefaultPollInterval
+	minRetryDelay = time.Second      // delay before retrying a failed poll, doubled on every further failure
+	maxRetryDelay = 30 * time.Second // bounds the delay before retrying a failed poll
 )
 
-var pollingStop chan bool
+var (
+	pollingStop      chan struct{}
+	pollingRefreshes chan chan<- refreshResult // asks the poll goroutine of Init to refresh, replying if not nil
+	pollingLock      sync.Mutex
+)
+
+// refreshResult is the outcome of a Refresh made by the poll goroutine.
+type refreshResult struct {
+	time    time.Time
+	devices []Device
+	err     error
+}
+
+// pollDevices enumerates the connected Devices for Init, Refresh and WaitFor; tests replace it.
+var pollDevices = getDevices
 
-// Stop will turn off the polling of new Devices.
+// Stop will turn off the polling of new Devices and release libusb.
 func Stop() {
-	select {
-	case pollingStop <- true:
-	default:
+	pollingLock.Lock()
+	if pollingStop != nil {
+		close(pollingStop)
+		pollingStop, pollingRefreshes = nil, nil
 	}
+	pollingLock.Unlock()
+
+	usbContextLock.Lock()
+	closeUSBContext()
+	usbContextLock.Unlock()
 }
 
-// Init will start the polling of Devices connected to the machine and return the current list of connected Devices.
-// It takes a callback function to be run anytime there is a change in Devices
-func Init(onUpdateCallback func([]Device)) []Device {
-	pollingStop = make(chan bool)
-	go func() {
-		_, initialDevices := Refresh()
+// Init polls the Devices connected to the machine once and returns them, then keeps polling in the background
+// and runs the callback with the Devices first and whenever they change, at the pace set by SetPolling. Failed
+// polls, reported by Health, are retried with a delay growing up to maxRetryDelay; the callback is run with nil
+// after each of them.
+//
+// A failed first poll is returned as a PollError, but the polling continues and may succeed later, e.g. once
+// a device node is made readable. Init returns ErrAlreadyStarted when called again before Stop.
+func Init(onUpdateCallback func([]Device)) ([]Device, error) {
+	pollingLock.Lock()
+	if pollingStop != nil {
+		pollingLock.Unlock()
+		return nil, ErrAlreadyStarted
+	}
+	stop := make(chan struct{})
+	refreshes := make(chan chan<- refreshResult, 1)
+	pollingStop, pollingRefreshes = stop, refreshes
+	pollingLock.Unlock()
+
+	setHealthState(HealthStarting)
+	logTime, initialDevices, err := refresh()
+	if err == nil {
+		addViolationLogs(initialDevices, logTime)
+	}
+	delay := getPolling().interval()
+	if err != nil {
+		delay = retryDelay(1)
+	}
+	recordPollResult(err, delay)
+
+	go poll(stop, refreshes, initialDevices, err, onUpdateCallback)
+
+	return initialDevices, err
+}
+
+// poll runs the callback of Init with the initial Devices and those of the polls that changed them, until stop
+// is closed. Changes are held back as long as the Polling asks for, and only the last Devices are passed on.
+// The requests of Refresh and RequestRefresh received on refreshes are answered with an immediate poll.
+func poll(stop chan struct{}, refreshes <-chan chan<- refreshResult, devices []Device, err error, onUpdateCallback func([]Device)) {
+	defer func() {
+		pollingLock.Lock()
+		defer pollingLock.Unlock()
+		// Init may have been called again since Stop.
+		if pollingStop == nil {
+			setHealthState(HealthStopped)
+		}
+	}()
+
+	failures := 0
+	if err != nil {
+		failures = 1
+	}
+	initialized := err == nil
+	onUpdateCallback(devices)
+	lastUpdate := time.Now()
+
+	// pending holds the Devices of changes whose callback is held back until the update timer fires.
+	var pending []Device
+	var held bool
+	var firstChange time.Time
+	updateTimer := time.NewTimer(0)
+	updateTimer.Stop()
+	defer updateTimer.Stop()
+
+	for {
+		timing := getPolling()
+		delay := timing.interval()
+		if failures > 0 {
+			delay = retryDelay(failures)
+		}
+		var refreshing bool
+		var refreshed chan<- refreshResult
+		select {
+		case <-time.After(delay):
+		case refreshed = <-refreshes:
+			refreshing = true
+		case <-updateTimer.C:
+			onUpdateCallback(pending)
+			held, lastUpdate = false, time.Now()
+			continue
+		case <-stop:
+			return
+		}
+
+		var changed bool
+		var logTime time.Time
+		switch {
+		case !initialized:
+			logTime, devices, err = refresh()
+			changed, initialized = err == nil, err == nil
+			if initialized {
+				addViolationLogs(devices, logTime)
+			}
+		case refreshing:
+			var newDevices []Device
+			logTime, newDevices, err = pollDevices()
+			if err == nil {
+				changed, devices = true, resetDevices(newDevices, logTime)
+			}
+		default:
+			var newDevices []Device
+			logTime, newDevices, err = pollDevices()
+			if err == nil {
+				changed, devices = deviceDiff(newDevices, logTime)
+			}
+		}
+		if refreshed != nil {
+			result := refreshResult{time: logTime, err: err}
+			if err == nil {
+				result.devices = devices
+			}
+			refreshed <- result
+		}
 
-		for initialDevices == nil {
-			time.Sleep(1 * time.Second)
+		if err != nil {
+			failures++
+			recordPollResult(err, retryDelay(failures))
+			getLogger().Warn("poll failed", "failures", failures, "retry", retryDelay(failures), "error", err)
+			updateTimer.Stop()
+			held = false
 			onUpdateCallback(nil)
-			_, initialDevices = Refresh()
+			lastUpdate = time.Now()
+			continue
+		}
+		// The Devices are passed again after failures, which were signalled with nil.
+		if failures > 0 {
+			getLogger().Info("poll succeeded again", "failures", failures)
+			changed = true
+		}
+		failures = 0
+		recordPollResult(nil, 0)
+		if !changed {
+			continue
 		}
 
-		onUpdateCallback(initialDevices)
+		now := time.Now()
+		if !held {
+			firstChange = now
+		}
+		pending, held = devices, true
+		if wait := time.Until(timing.updateTime(firstChange, now, lastUpdate)); wait > 0 {
+			updateTimer.Reset(wait)
+			continue
+		}
+		updateTimer.Stop()
+		onUpdateCallback(pending)
+		held, lastUpdate = false, now
+	}
+}
 
-		ticker := time.NewTicker(250 * time.Millisecond)
-		defer ticker.Stop()
+// Refresh resets the cached Device state to that of the current devices connected to the machine: the Devices
+// removed until now are dropped, and the others are no longer marked as added. While Init polls, the poll is
+// made by its goroutine, which first publishes the changes since its last poll, and passes the Devices to the
+// callback of Init.
+func Refresh() (time.Time, []Device, error) {
+	pollingLock.Lock()
+	stop, refreshes := pollingStop, pollingRefreshes
+	pollingLock.Unlock()
+	if refreshes == nil {
+		return refresh()
+	}
 
-		for {
-			select {
-			case <-ticker.C:
-				logTime, newDevices := getDevices()
+	// The reply is buffered so that the poll goroutine never waits for it. Refresh must not be called from the
+	// callback of Init, which runs on the poll goroutine.
+	reply := make(chan refreshResult, 1)
+	select {
+	case refreshes <- reply:
+	case <-stop:
+		return refresh()
+	}
+	select {
+	case result := <-reply:
+		return result.time, result.devices, result.err
+	case <-stop:
+		return time.Now(), nil, ErrStopped
+	}
+}
 
-				if newDevices != nil {
-					changed, mergedDevices := deviceDiff(newDevices, logTime)
-					if changed {
-						onUpdateCallback(mergedDevices)
-					}
-				} else {
-					onUpdateCallback(nil)
-				}
+// RequestRefresh is Refresh without waiting for the Devices: while Init polls, they are passed to its callback.
+// It returns the time of the request.
+func RequestRefresh() time.Time {
+	requestTime := time.Now()
+
+	pollingLock.Lock()
+	refreshes := pollingRefreshes
+	pollingLock.Unlock()
+	if refreshes == nil {
+		_, _, _ = refresh()
+		return requestTime
+	}
 
-			case <-pollingStop:
-				close(pollingStop)
-				return
-			}
-		}
-	}()
+	select {
+	case refreshes <- nil:
+	default:
+		// A refresh is already pending.
+	}
 
-	return cachedDevices
+	return requestTime
 }
 
-// Refresh resets the cached Device state to that of the current devices connected to the machine.
-func Refresh() (time.Time, []Device) {
-	logTime, retrievedDevices := getDevices()
-	if retrievedDevices != nil {
-		cachedDevices = sortDevices(retrievedDevices)
-		lastMergedMap = nil
-		return logTime, cachedDevices
+// refresh enumerates the Devices and makes them the reference of the next diff, without publishing anything.
+// It is only called when no other goroutine polls, or by the poll goroutine itself.
+func refresh() (time.Time, []Device, error) {
+	logTime, retrievedDevices, err := pollDevices()
+	if err != nil {
+		return logTime, nil, err
 	}
 
-	return logTime, nil
+	return logTime, setReference(retrievedDevices), nil
 }
 
-// returns lists of devices.
-func getDevices() (time.Time, []Device) {
-	ctx := gousb.NewContext()
-	defer func() {
-		if err := ctx.Close(); err != nil {
-			addErrorLog(fmt.Sprintf("Error trying to get USB devices: %s", err.Error()), time.Now(), StateError)
+// resetDevices publishes the changes between the last poll and the new Devices, then makes the new Devices the
+// reference of the next diff, all marked as normal.
+func resetDevices(newDevices []Device, logTime time.Time) []Device {
+	deviceDiff(newDevices, logTime)
+
+	return setReference(newDevices)
+}
+
+// setReference makes the Devices the reference of the next diff, all marked as normal, and returns them sorted.
+func setReference(devices []Device) []Device {
+	cachedDevices = sortDevices(devices)
+	lastMergedMap = make(map[string]Device, len(cachedDevices))
+	for i := range cachedDevices {
+		cachedDevices[i].State = StateNormal
+		lastMergedMap[cachedDevices[i].Key()] = cachedDevices[i]
+	}
+
+	return slices.Clone(cachedDevices)
+}
+
+var (
+	// usbContext is the libusb context kept open between polls, since opening one scans all devices.
+	usbContext     *gousb.Context
+	usbContextLock sync.Mutex
+)
+
+// getDevices enumerates the Devices connected to the machine. Errors are PollErrors, also added to the log.
+func getDevices() (time.Time, []Device, error) {
+	usbContextLock.Lock()
+	defer usbContextLock.Unlock()
+
+	if usbContext == nil {
+		ctx, err := newUSBContext()
+		if err != nil {
+			return failedPoll(usbError(ErrLibusbUnavailable, err))
 		}
-	}()
+		usbContext = ctx
+	}
 
-	var devices []Device
+	devices := []Device{}
+	cfg := GetConfig()
+	chain := activeEnrichers(cfg)
+	for _, enricher := range chain {
+		if starter, ok := enricher.(pollStarter); ok {
+			starter.startPoll()
+		}
+	}
+	timings := make([]time.Duration, len(chain))
+	start := time.Now()
+	var enrichDuration time.Duration
+	var enrichErr *PollError
 
-	_, err := ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+	_, err := usbContext.OpenDevices(func(desc *gousb.DeviceDesc) bool {
 		device := descToDevice(*desc)
-		if device.enrich() {
+		enrichStart := time.Now()
+		skip, pollErr := device.enrichWith(chain, timings)
+		enrichDuration += time.Since(enrichStart)
+		if pollErr != nil {
+			enrichErr = pollErr
+		} else if !skip {
+			device.applyPolicy(cfg)
 			devices = append(devices, device)
 		}
 		return false
 	})
 	if err != nil {
+		// A fresh context is opened for the next poll, in case this one is broken.
+		closeUSBContext()
+		return failedPoll(usbError(ErrEnumerationFailed, err))
+	}
+	if enrichErr != nil {
+		return failedPoll(enrichErr)
+	}
+	pollDuration := time.Since(start)
+	recordPoll(pollDuration, pollDuration-enrichDuration)
+	hits, misses := takeDeviceInfoCacheStats()
+	getLogger().Debug("polled USB devices", "devices", len(devices), "duration", pollDuration,
+		"enumeration", pollDuration-enrichDuration, "cache_hits", hits, "cache_misses", misses,
+		slog.Group("enrichers", enricherTimings(chain, timings)...))
+
+	return time.Now(), devices, nil
+}
+
+// closeUSBContext closes the libusb context kept between polls. usbContextLock must be held.
+func closeUSBContext() {
+	if usbContext == nil {
+		return
+	}
+	if err := usbContext.Close(); err != nil {
+		getLogger().Warn("closing the libusb context failed", "error", err)
 		addErrorLog(fmt.Sprintf("Error trying to get USB devices: %s", err.Error()), time.Now(), StateError)
-		return time.Now(), nil
 	}
+	usbContext = nil
+}
+
+// failedPoll reports the error of a poll and returns it as the result of getDevices.
+func failedPoll(err *PollError) (time.Time, []Device, error) {
+	getLogger().Error("polling USB devices failed", "kind", err.Kind, "error", err.Err)
+	addErrorLog(fmt.Sprintf("Error trying to get USB devices: %s", err.Error()), time.Now(), StateError)
 
-	return time.Now(), devices
+	return time.Now(), nil, err
 }
 
-// Returns a device based on a given DeviceDesc
+// newUSBContext opens a libusb context. gousb panics when libusb cannot be initialised, which is returned as
+// an error instead.
+func newUSBContext() (ctx *gousb.Context, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if err, _ = r.(error); err == nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	return gousb.NewContext(), nil
+}
+
+// Returns a device based on a given DeviceDesc, to be named by the Enrichers.
 func descToDevice(desc gousb.DeviceDesc) Device {
+	config, _ := firstConfig(desc)
+
 	return Device{
 		Bus:       desc.Bus,
 		Path:      desc.Path,
-		Name:      usbid.Describe(&desc),
 		VendorID:  desc.Vendor.String(),
 		ProductID: desc.Product.String(),
 		Speed:     desc.Speed.String(),
 		State:     StateNormal,
 		DevNum:    desc.Address,
+		Class:     className(desc.Class),
+
+		Firmware:    desc.Device.String(),
+		USBVersion:  desc.Spec.String(),
+		MaxPower:    int(config.MaxPower),
+		SelfPowered: config.SelfPowered,
+
+		Interfaces: interfacesFromDesc(desc),
 	}
 }
 
-// Key returns a unique string identifier for the device using its bus, path, vendor ID, product ID, and speed attributes.
+// usbIDNames looks up the vendor and product names of the device in the USB ID database.
+func usbIDNames(desc gousb.DeviceDesc) (vendorName, productName string) {
+	vendor, ok := usbid.Vendors[desc.Vendor]
+	if !ok {
+		return "", ""
+	}
+	if product, ok := vendor.Product[desc.Product]; ok {
+		productName = product.Name
+	}
+
+	return vendor.Name, productName
+}
+
+// Key returns a unique string identifier for the device using its bus, path, vendor ID, product ID, and speed attributes,
+// prefixed by its Host when set.
 func (d *Device) Key() string {
-	return fmt.Sprintf("%d:%v:%s:%s:%s", d.Bus, d.Path, d.VendorID, d.ProductID, d.Speed)
+	key := fmt.Sprintf("%d:%v:%s:%s:%s", d.Bus, d.Path, d.VendorID, d.ProductID, d.Speed)
+	if d.Host != "" {
+		return d.Host + "/" + key
+	}
+
+	return key
+}
+
+// PortPath returns the physical location of the device in the sysfs notation, e.g. "1-3.2" for port 2 of a
+// hub on port 3 of bus 1. Root hubs are named after their bus, e.g. "usb1".
+func (d *Device) PortPath() string {
+	if len(d.Path) == 0 {
+		return fmt.Sprintf("usb%d", d.Bus)
+	}
+
+	ports := make([]string, len(d.Path))
+	for i, port := range d.Path {
+		ports[i] = strconv.Itoa(port)
+	}
+
+	return fmt.Sprintf("%d-%s", d.Bus, strings.Join(ports, "."))
+}
+
+// sameDetails reports whether the user-visible details of two versions of the same device match.
+func (d *Device) sameDetails(other Device) bool {
+	return d.Name == other.Name && d.Serial == other.Serial &&
+		d.Alias == other.Alias && d.Note == other.Note && d.Color == other.Color &&
+		d.PortLabel == other.PortLabel && slices.Equal(d.Violations, other.Violations)
 }
 
 func deviceDiff(newDevices []Device, logTime time.Time) (changed bool, merged []Device) {
 	mergedMap := make(map[string]Device)
 	changed = false
+	var events []Event
 
 	// Mark all cachedDevices as removed initially
 	for _, device := range cachedDevices {
@@ -174,10 +537,10 @@ func deviceDiff(newDevices []Device, logTime time.Time) (changed bool, merged []
 	// Reset persisting devices to normal and add new devices
 	for _, newDevice := range newDevices {
 		key := newDevice.Key()
-		if existingDevice, exists := mergedMap[key]; exists {
-			// Device exists, reset its status to normal
-			existingDevice.State = StateNormal
-			mergedMap[key] = existingDevice
+		if _, exists := mergedMap[key]; exists {
+			// Device exists, reset its status to normal and keep its latest details
+			newDevice.State = StateNormal
+			mergedMap[key] = newDevice
 		} else {
 			// Device is new, add to mergedMap
 			newDevice.State = StateAdded
@@ -192,6 +555,7 @@ func deviceDiff(newDevices []Device, logTime time.Time) (changed bool, merged []
 			device.State = StateRemoved
 			clearPriorityNameCache(device)
 			addDeviceLog(device, logTime)
+			events = append(events, Event{Time: logTime, Type: EventRemoved, Device: device})
 			changed = true
 		}
 	}
@@ -203,17 +567,36 @@ func deviceDiff(newDevices []Device, logTime time.Time) (changed bool, merged []
 
 		if lastDevice, exists := lastMergedMap[key]; !exists {
 			addDeviceLog(device, logTime)
+			events = append(events, Event{Time: logTime, Type: eventType(device.State), Device: device})
+			if len(device.Violations) > 0 && device.State != StateRemoved {
+				addViolationLog(device, logTime)
+				events = append(events, Event{Time: logTime, Type: EventViolation, Device: device})
+			}
 			changed = true
 		} else if device.State != lastDevice.State {
 			if device.State == StateRemoved {
 				clearPriorityNameCache(device)
 			}
 			addDeviceLog(device, logTime)
+			events = append(events, Event{Time: logTime, Type: eventType(device.State), Device: device})
+			changed = true
+		} else if !device.sameDetails(lastDevice) {
+			events = append(events, Event{Time: logTime, Type: EventChanged, Device: device})
 			changed = true
 		}
 	}
 
 	merged = sortDevices(merged)
+	if changed {
+		getLogger().Debug("devices changed", "devices", len(merged), "events", len(events), "initial", lastMergedMap == nil)
+	}
+
+	if lastMergedMap != nil {
+		sort.SliceStable(events, func(i, j int) bool {
+			return lessDevice(events[i].Device, events[j].Device)
+		})
+		publish(events)
+	}
 
 	lastMergedMap = mergedMap
 
@@ -221,8 +604,8 @@ func deviceDiff(newDevices []Device, logTime time.Time) (changed bool, merged []
 }
 
 // BuildDeviceTree converts a device list to a device tree
-func BuildDeviceTree(devices []Device) []*TreeNode {
-	var roots []*TreeNode
+func BuildDeviceTree(devices []Device) Tree {
+	var roots Tree
 	var nodes []*TreeNode
 
 	for _, dev := range devices {
@@ -290,17 +673,29 @@ func flatten(path []int) string {
 // sortDevices sorts devices consistently by their path
 func sortDevices(devices []Device) []Device {
 	sort.Slice(devices, func(i, j int) bool {
-		if devices[i].Bus != devices[j].Bus {
-			return devices[i].Bus < devices[j].Bus
-		}
-
-		return flatten(devices[i].Path) < flatten(devices[j].Path)
+		return lessDevice(devices[i], devices[j])
 	})
 
 	return devices
 }
 
+// lessDevice orders devices by bus, then by path.
+func lessDevice(a, b Device) bool {
+	if a.Bus != b.Bus {
+		return a.Bus < b.Bus
+	}
+
+	return flatten(a.Path) < flatten(b.Path)
+}
+
 func addErrorLog(text string, logTime time.Time, state LogState) {
+	if state == StateError {
+		countError()
+	}
+
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
 	logs = append(logs, Log{Time: logTime, Text: text, State: state})
 }
 
@@ -314,10 +709,22 @@ func addDeviceLog(device Device, logTime time.Time) {
 		logState = StateAdded
 	}
 
-	logs = append(logs, Log{Time: logTime, Text: device.Name, State: logState, Speed: device.Speed})
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	logs = append(logs, Log{
+		Time:      logTime,
+		Text:      device.DisplayName(),
+		State:     logState,
+		Speed:     device.Speed,
+		PortLabel: device.PortLabel,
+	})
 }
 
 // GetLog returns all stored device logs.
 func GetLog() []Log {
-	return logs
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	return slices.Clone(logs)
 }
@@ -0,0 +1,198 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gousb"
+	"github.com/google/gousb/usbid"
+)
+
+// An Enricher adds the details one source has about a Device to those read from its descriptor.
+//
+// The Enrichers run in the order of the registry, and each keeps the details set by those before it: the first
+// one to know a detail has precedence. The built-in ones are, in order:
+//
+//   - "udev": names, speed, serial, device node, ports and host controller from the udev database (Linux)
+//   - "sysfs-power": runtime power management state from sysfs (Linux)
+//   - "drivers": kernel drivers of the device and its interfaces (Linux)
+//   - "usb-ids": vendor and product names from the embedded usb.ids database
+//   - "aliases": aliases, notes, colors and port labels from the Config
+//
+// They can be switched off in the enrichers section of the Config. The security policy is applied after them
+// and cannot be switched off.
+type Enricher interface {
+	// Name identifies the Enricher in the Config and in the debug log.
+	Name() string
+	// Enrich adds details to the Device. It returns ErrSkipDevice to leave the Device out of the poll and a
+	// PollError to fail the poll; other errors are logged, and the Device is kept.
+	Enrich(*Device) error
+}
+
+// ErrSkipDevice is returned by an Enricher to leave a Device out of the poll, e.g. because udev has not
+// processed it yet. It will be polled again.
+var ErrSkipDevice = errors.New("skip the device")
+
+// pollStarter is implemented by the Enrichers that keep state for the duration of one poll.
+type pollStarter interface {
+	startPoll()
+}
+
+var (
+	enrichers     = defaultEnrichers()
+	enrichersLock sync.RWMutex
+)
+
+// defaultEnrichers returns the built-in Enrichers of the platform in the order they run.
+func defaultEnrichers() []Enricher {
+	return append(platformEnrichers(), usbIDsEnricher{}, aliasEnricher{})
+}
+
+// RegisterEnricher adds an Enricher after the others, so that it fills in what they leave out. An Enricher
+// registered under the name of another replaces it in its position.
+func RegisterEnricher(enricher Enricher) {
+	enrichersLock.Lock()
+	defer enrichersLock.Unlock()
+
+	enrichers = slices.Clone(enrichers)
+	for i, other := range enrichers {
+		if other.Name() == enricher.Name() {
+			enrichers[i] = enricher
+			return
+		}
+	}
+	enrichers = append(enrichers, enricher)
+}
+
+// Enrichers returns the names of the registered Enrichers in the order they run.
+func Enrichers() []string {
+	enrichersLock.RLock()
+	defer enrichersLock.RUnlock()
+
+	names := make([]string, len(enrichers))
+	for i, enricher := range enrichers {
+		names[i] = enricher.Name()
+	}
+
+	return names
+}
+
+// activeEnrichers returns the registered Enrichers the Config does not switch off.
+func activeEnrichers(cfg Config) []Enricher {
+	enrichersLock.RLock()
+	defer enrichersLock.RUnlock()
+
+	var active []Enricher
+	for _, enricher := range enrichers {
+		if enabled, ok := cfg.Enrichers[enricher.Name()]; !ok || enabled {
+			active = append(active, enricher)
+		}
+	}
+
+	return active
+}
+
+// validateEnrichers checks that the enrichers section of the Config names registered Enrichers.
+func validateEnrichers(switches map[string]bool) error {
+	names := Enrichers()
+	for name := range switches {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("enrichers: unknown enricher %q, expected one of %s", name, strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
+
+// enrichWith runs the Enrichers on the Device, adding the time each took to timings. It reports whether the
+// Device is to be skipped, and returns the error failing the poll if any.
+func (d *Device) enrichWith(chain []Enricher, timings []time.Duration) (skip bool, pollErr *PollError) {
+	for i, enricher := range chain {
+		start := time.Now()
+		err := enricher.Enrich(d)
+		timings[i] += time.Since(start)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrSkipDevice):
+			getLogger().Debug("skipping device", "device", d.Key(), "enricher", enricher.Name())
+			return true, nil
+		case errors.As(err, &pollErr):
+			return false, pollErr
+		default:
+			getLogger().Warn("enriching the device failed", "device", d.Key(), "enricher", enricher.Name(), "error", err)
+		}
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		d.Name = fmt.Sprintf("Unknown %s:%s", d.VendorID, d.ProductID)
+	}
+
+	return false, nil
+}
+
+// enricherTimings returns the time each Enricher took as attributes for the debug log.
+func enricherTimings(chain []Enricher, timings []time.Duration) []any {
+	attrs := make([]any, len(chain))
+	for i, enricher := range chain {
+		attrs[i] = slog.Duration(enricher.Name(), timings[i])
+	}
+
+	return attrs
+}
+
+// usbIDsEnricher names Devices after the embedded usb.ids database.
+type usbIDsEnricher struct{}
+
+func (usbIDsEnricher) Name() string {
+	return "usb-ids"
+}
+
+func (usbIDsEnricher) Enrich(d *Device) error {
+	vendor, err := strconv.ParseUint(d.VendorID, 16, 16)
+	if err != nil {
+		return nil
+	}
+	product, err := strconv.ParseUint(d.ProductID, 16, 16)
+	if err != nil {
+		return nil
+	}
+	desc := gousb.DeviceDesc{Vendor: gousb.ID(vendor), Product: gousb.ID(product)}
+
+	vendorName, productName := usbIDNames(desc)
+	if strings.TrimSpace(d.Name) == "" {
+		d.Name = usbid.Describe(&desc)
+	}
+	if d.VendorName == "" {
+		d.VendorName = vendorName
+	}
+	if d.ProductName == "" {
+		d.ProductName = productName
+	}
+
+	return nil
+}
+
+// aliasEnricher applies the aliases and port labels of the Config.
+type aliasEnricher struct{}
+
+func (aliasEnricher) Name() string {
+	return "aliases"
+}
+
+func (aliasEnricher) Enrich(d *Device) error {
+	cfg := GetConfig()
+	if d.Alias == "" && d.Note == "" && d.Color == "" {
+		d.applyAliases(cfg)
+	}
+	if d.PortLabel == "" {
+		d.applyPortLabel(cfg)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnricher sets the Note of Devices it has not been set yet, and returns err.
+type fakeEnricher struct {
+	name string
+	err  error
+}
+
+func (e fakeEnricher) Name() string {
+	return e.name
+}
+
+func (e fakeEnricher) Enrich(d *Device) error {
+	if d.Note == "" {
+		d.Note = "from " + e.name
+	}
+	return e.err
+}
+
+func TestUSBIDsEnricher(t *testing.T) {
+	dev := descToDevice(mockDesc())
+	require.NoError(t, usbIDsEnricher{}.Enrich(&dev))
+	assert.Equal(t, "3.0 root hub (Linux Foundation)", dev.Name)
+	assert.Equal(t, "Linux Foundation", dev.VendorName)
+	assert.Equal(t, "3.0 root hub", dev.ProductName)
+
+	named := Device{Name: "Root hub", VendorID: "1d6b", ProductID: "0003"}
+	require.NoError(t, usbIDsEnricher{}.Enrich(&named))
+	assert.Equal(t, "Root hub", named.Name, "an earlier enricher has precedence")
+}
+
+func TestEnrichWith(t *testing.T) {
+	chain := []Enricher{fakeEnricher{name: "first"}, fakeEnricher{name: "second", err: errors.New("flaky")}}
+	timings := make([]time.Duration, len(chain))
+	device := Device{VendorID: "abcd", ProductID: "0001"}
+
+	skip, pollErr := device.enrichWith(chain, timings)
+	assert.False(t, skip)
+	assert.Nil(t, pollErr, "other errors are only logged")
+	assert.Equal(t, "from first", device.Note)
+	assert.Equal(t, "Unknown abcd:0001", device.Name)
+
+	skip, _ = device.enrichWith([]Enricher{fakeEnricher{name: "skip", err: ErrSkipDevice}}, timings)
+	assert.True(t, skip)
+
+	failure := &PollError{Kind: ErrUdevFailure, Err: errors.New("no udev")}
+	_, pollErr = device.enrichWith([]Enricher{fakeEnricher{name: "fail", err: failure}}, timings)
+	assert.Same(t, failure, pollErr)
+}
+
+func TestActiveEnrichers(t *testing.T) {
+	names := func(chain []Enricher) []string {
+		var names []string
+		for _, enricher := range chain {
+			names = append(names, enricher.Name())
+		}
+		return names
+	}
+
+	assert.Equal(t, Enrichers(), names(activeEnrichers(Config{})))
+	active := names(activeEnrichers(Config{Enrichers: map[string]bool{"usb-ids": false, "aliases": true}}))
+	assert.NotContains(t, active, "usb-ids")
+	assert.Contains(t, active, "aliases")
+}
+
+func TestRegisterEnricher(t *testing.T) {
+	registered := enrichers
+	t.Cleanup(func() { enrichers = registered })
+
+	RegisterEnricher(fakeEnricher{name: "inventory"})
+	assert.Equal(t, "inventory", Enrichers()[len(Enrichers())-1])
+	RegisterEnricher(fakeEnricher{name: "usb-ids"})
+	assert.Equal(t, len(registered)+1, len(Enrichers()), "replaced in its position")
+}
+
+func TestLoadConfig_Enrichers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("enrichers: {usb-ids: false}\n"), 0o644))
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"usb-ids": false}, cfg.Enrichers)
+
+	require.NoError(t, os.WriteFile(path, []byte("enrichers: {usbids: false}\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, `unknown enricher "usbids"`)
+}
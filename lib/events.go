@@ -0,0 +1,96 @@
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// An EventType describes how a Device changed between two polls.
+type EventType string
+
+// These constants represent the type of an Event.
+const (
+	EventAdded   EventType = "added"
+	EventRemoved EventType = "removed"
+	EventChanged EventType = "changed"
+
+	// EventViolation is published alongside EventAdded for Devices breaking the security policy.
+	EventViolation EventType = "violation"
+)
+
+// EventTypes returns every EventType.
+func EventTypes() []EventType {
+	return []EventType{EventAdded, EventRemoved, EventChanged, EventViolation}
+}
+
+// An Event records a Device being added, removed or changed, or a policy violation.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Type   EventType `json:"type"`
+	Device Device    `json:"device"`
+}
+
+// eventType returns the type of Event logged for a Device with the given state.
+func eventType(state LogState) EventType {
+	if state == StateRemoved {
+		return EventRemoved
+	}
+
+	return EventAdded
+}
+
+// subscriberBuffer is the number of Events a subscriber may fall behind before Events are dropped.
+const subscriberBuffer = 256
+
+var (
+	subscribers     = make(map[chan Event]struct{})
+	subscribersLock sync.Mutex
+)
+
+// Subscribe returns a channel receiving every Event published after the call, and a function that
+// unsubscribes and closes the channel. Events are dropped, with an error log, when the channel is full.
+func Subscribe() (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+
+	subscribersLock.Lock()
+	subscribers[events] = struct{}{}
+	subscribersLock.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			subscribersLock.Lock()
+			delete(subscribers, events)
+			subscribersLock.Unlock()
+			close(events)
+		})
+	}
+}
+
+// publish hands the Events found by a diff to the rules engine and the subscribers.
+func publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	rules := GetConfig().Rules
+	for _, event := range events {
+		getLogger().Info("device event", "type", event.Type, "device", event.Device.Key(), "vid", event.Device.VendorID,
+			"pid", event.Device.ProductID, "serial", event.Device.Serial)
+		countEvent(event)
+		runRules(rules, event)
+	}
+
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	for subscriber := range subscribers {
+		for _, event := range events {
+			select {
+			case subscriber <- event:
+			default:
+				getLogger().Warn("event subscriber is not keeping up, dropped an event", "type", event.Type, "device", event.Device.Key())
+				addErrorLog("Event subscriber is not keeping up, dropped an event", event.Time, StateError)
+			}
+		}
+	}
+}
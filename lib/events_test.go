@@ -0,0 +1,38 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	fakeRefresh([]Device{device1})
+	events, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	deviceDiff([]Device{device1, device2}, time.Now())
+	deviceDiff([]Device{device2}, time.Now())
+
+	require.Len(t, events, 2)
+	added := <-events
+	assert.Equal(t, EventAdded, added.Type)
+	assert.Equal(t, device2.Key(), added.Device.Key())
+	removed := <-events
+	assert.Equal(t, EventRemoved, removed.Type)
+	assert.Equal(t, device1.Key(), removed.Device.Key())
+}
+
+func TestSubscribe_Unsubscribe(t *testing.T) {
+	fakeRefresh([]Device{device1})
+	events, unsubscribe := Subscribe()
+	unsubscribe()
+	unsubscribe()
+
+	deviceDiff([]Device{device1, device2}, time.Now())
+
+	_, open := <-events
+	assert.False(t, open, "the channel should be closed")
+}
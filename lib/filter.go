@@ -0,0 +1,371 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Filter selects Devices with an expression of space-separated terms, all of which must match, such as
+//
+//	vid:046d class:hid speed<480 bus:3 name~"keyboard" state:added port:1-2.*
+//
+// A term is a key, an operator and a value, which is quoted when it contains spaces. The operators are:
+//
+//   - ":" equals, ignoring case; the value may use the wildcards "*" and "?", such as "*hub*" or "1-2.*"
+//   - "~" contains, ignoring case
+//   - "<", "<=", ">" and ">=" compare numbers
+//
+// The text keys vid, pid, serial, name, alias, vendor, product, driver, label and host accept ":" and "~",
+// port accepts ":", with shell patterns such as "1-[23].*", and "~", and class (a name such as "hid" or a hex
+// code such as "03") and state accept ":". The number keys speed (in Mbps, or a speed name such as "high"),
+// bus, devnum, ports and power (in mA) accept every operator but "~". A term without a key, such as
+// "keyboard", matches the Devices whose serial, name, alias or vid:pid contain it, and a "046d:c31c" term
+// matches both the vendor and the product ID.
+//
+// The zero Filter matches every Device.
+type Filter struct {
+	text  string
+	terms []filterTerm
+}
+
+// A FilterError reports a term of a filter expression that could not be parsed. Err wraps one of
+// ErrFilterSyntax, ErrUnknownFilterKey, ErrFilterOperator and ErrFilterValue.
+type FilterError struct {
+	Column int    // column of the term in the expression, starting at 1
+	Term   string // the term as written
+	Err    error
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("filter term %q at column %d: %v", e.Term, e.Column, e.Err)
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrFilterSyntax is reported for terms that are malformed, such as an unterminated quote or a missing value.
+	ErrFilterSyntax = errors.New("syntax error")
+	// ErrUnknownFilterKey is reported for terms with a key the Filter does not know.
+	ErrUnknownFilterKey = errors.New("unknown key")
+	// ErrFilterOperator is reported for terms with an operator their key does not support.
+	ErrFilterOperator = errors.New("unsupported operator")
+	// ErrFilterValue is reported for terms with a value their key does not accept.
+	ErrFilterValue = errors.New("invalid value")
+)
+
+// filterOperators are the operators of the terms, the longest first so that "<=" is not read as "<".
+var filterOperators = []string{"<=", ">=", ":", "~", "<", ">"}
+
+// A filterTerm is one parsed term of a Filter.
+type filterTerm struct {
+	key    string // empty for terms without a key
+	op     string
+	value  string  // lowercase for text keys
+	number float64 // value of the number keys
+}
+
+// filterKind is the type of the values a filter key compares.
+type filterKind int
+
+const (
+	filterText filterKind = iota
+	filterPort
+	filterClass
+	filterState
+	filterNumber
+)
+
+// A filterField is a key of the Filter and how it reads the Device.
+type filterField struct {
+	kind   filterKind
+	text   func(Device) string
+	number func(Device) float64
+}
+
+var filterFields = map[string]filterField{
+	"vid":     {kind: filterText, text: func(d Device) string { return d.VendorID }},
+	"pid":     {kind: filterText, text: func(d Device) string { return d.ProductID }},
+	"serial":  {kind: filterText, text: func(d Device) string { return d.Serial }},
+	"name":    {kind: filterText, text: func(d Device) string { return d.Name }},
+	"alias":   {kind: filterText, text: func(d Device) string { return d.Alias }},
+	"vendor":  {kind: filterText, text: func(d Device) string { return d.VendorName }},
+	"product": {kind: filterText, text: func(d Device) string { return d.ProductName }},
+	"driver":  {kind: filterText, text: func(d Device) string { return d.Driver }},
+	"label":   {kind: filterText, text: func(d Device) string { return d.PortLabel }},
+	"host":    {kind: filterText, text: func(d Device) string { return d.Host }},
+	"port":    {kind: filterPort, text: func(d Device) string { return d.PortPath() }},
+	"class":   {kind: filterClass},
+	"state":   {kind: filterState},
+	"speed":   {kind: filterNumber, number: func(d Device) float64 { return speedMbps(d.Speed) }},
+	"bus":     {kind: filterNumber, number: func(d Device) float64 { return float64(d.Bus) }},
+	"devnum":  {kind: filterNumber, number: func(d Device) float64 { return float64(d.DevNum) }},
+	"ports":   {kind: filterNumber, number: func(d Device) float64 { return float64(d.Ports) }},
+	"power":   {kind: filterNumber, number: func(d Device) float64 { return float64(d.MaxPower) }},
+}
+
+// filterKeys returns the keys of the Filter in alphabetical order, for error messages.
+func filterKeys() string {
+	keys := make([]string, 0, len(filterFields))
+	for key := range filterFields {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	return strings.Join(keys, ", ")
+}
+
+// ParseFilter parses a filter expression. Errors are *FilterError values pointing at the offending term.
+func ParseFilter(text string) (Filter, error) {
+	filter := Filter{text: strings.TrimSpace(text)}
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		quoted := false
+		for ; i < len(text) && (quoted || (text[i] != ' ' && text[i] != '\t')); i++ {
+			switch {
+			case text[i] == '"':
+				quoted = !quoted
+			case text[i] == '\\' && quoted && i+1 < len(text):
+				i++
+			}
+		}
+		raw := text[start:i]
+		if quoted {
+			return Filter{}, &FilterError{Column: start + 1, Term: raw, Err: fmt.Errorf("%w: unterminated quote", ErrFilterSyntax)}
+		}
+
+		term, err := parseFilterTerm(raw)
+		if err != nil {
+			return Filter{}, &FilterError{Column: start + 1, Term: raw, Err: err}
+		}
+		filter.terms = append(filter.terms, term)
+	}
+
+	return filter, nil
+}
+
+// parseFilterTerm parses one term of a filter expression.
+func parseFilterTerm(raw string) (filterTerm, error) {
+	keyEnd := 0
+	for keyEnd < len(raw) && isFilterKeyChar(raw[keyEnd]) {
+		keyEnd++
+	}
+	var op string
+	for _, operator := range filterOperators {
+		if keyEnd > 0 && strings.HasPrefix(raw[keyEnd:], operator) {
+			op = operator
+			break
+		}
+	}
+	if op == "" {
+		return filterTerm{value: strings.ToLower(unquote(raw))}, nil
+	}
+
+	key := strings.ToLower(raw[:keyEnd])
+	value := unquote(raw[keyEnd+len(op):])
+	if value == "" {
+		return filterTerm{}, fmt.Errorf("%w: missing value after %q", ErrFilterSyntax, raw[:keyEnd]+op)
+	}
+
+	field, ok := filterFields[key]
+	if !ok {
+		if op == ":" && isHexID(key) && isHexID(value) {
+			return filterTerm{key: "vid:pid", op: op, value: key + ":" + strings.ToLower(value)}, nil
+		}
+		return filterTerm{}, fmt.Errorf("%w %q, expected one of %s", ErrUnknownFilterKey, key, filterKeys())
+	}
+
+	term := filterTerm{key: key, op: op, value: value}
+	switch field.kind {
+	case filterText, filterPort:
+		if op != ":" && op != "~" {
+			return filterTerm{}, fmt.Errorf("%w %q for %s, expected : or ~", ErrFilterOperator, op, key)
+		}
+		if field.kind == filterText {
+			term.value = strings.ToLower(value)
+		}
+	case filterClass, filterState:
+		if op != ":" {
+			return filterTerm{}, fmt.Errorf("%w %q for %s, expected :", ErrFilterOperator, op, key)
+		}
+		if field.kind == filterState && !slices.Contains([]LogState{StateNormal, StateAdded, StateRemoved}, LogState(value)) {
+			return filterTerm{}, fmt.Errorf("%w %q for state, expected normal, added or removed", ErrFilterValue, value)
+		}
+	case filterNumber:
+		if op == "~" {
+			return filterTerm{}, fmt.Errorf("%w %q for %s, expected :, <, <=, > or >=", ErrFilterOperator, op, key)
+		}
+		if key == "speed" {
+			term.number = speedMbps(value)
+			if term.number == 0 {
+				return filterTerm{}, fmt.Errorf("%w %q for speed, expected Mbps or a speed name such as high", ErrFilterValue, value)
+			}
+			break
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return filterTerm{}, fmt.Errorf("%w %q for %s, expected a number", ErrFilterValue, value, key)
+		}
+		term.number = float64(number)
+	}
+
+	return term, nil
+}
+
+func isFilterKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// unquote removes the quotes of a term and the backslashes escaping characters inside them.
+func unquote(s string) string {
+	if !strings.Contains(s, `"`) {
+		return s
+	}
+
+	var unquoted strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '\\' && quoted && i+1 < len(s):
+			i++
+			unquoted.WriteByte(s[i])
+		default:
+			unquoted.WriteByte(s[i])
+		}
+	}
+
+	return unquoted.String()
+}
+
+// IsZero reports whether the Filter has no terms.
+func (f Filter) IsZero() bool {
+	return len(f.terms) == 0
+}
+
+// Matches reports whether the Device matches every term of the Filter.
+func (f Filter) Matches(device Device) bool {
+	for _, term := range f.terms {
+		if !term.matches(device) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t filterTerm) matches(device Device) bool {
+	switch t.key {
+	case "":
+		for _, field := range []string{device.Serial, device.Name, device.Alias, device.VendorID + ":" + device.ProductID} {
+			if strings.Contains(strings.ToLower(field), t.value) {
+				return true
+			}
+		}
+		return false
+	case "vid:pid":
+		return t.value == strings.ToLower(device.VendorID+":"+device.ProductID)
+	}
+
+	field := filterFields[t.key]
+	switch field.kind {
+	case filterText:
+		text := strings.ToLower(field.text(device))
+		if t.op == "~" {
+			return strings.Contains(text, t.value)
+		}
+		return matchWildcard(t.value, text)
+	case filterPort:
+		if t.op == "~" {
+			return strings.Contains(field.text(device), t.value)
+		}
+		return matchPort(t.value, field.text(device))
+	case filterClass:
+		return device.HasClass(t.value)
+	case filterState:
+		return device.State == LogState(t.value)
+	}
+
+	number := field.number(device)
+	if t.key == "speed" && number == 0 {
+		return false // unknown speed
+	}
+	switch t.op {
+	case "<":
+		return number < t.number
+	case "<=":
+		return number <= t.number
+	case ">":
+		return number > t.number
+	case ">=":
+		return number >= t.number
+	}
+	return number == t.number
+}
+
+// matchWildcard reports whether the text equals the pattern, in which "*" matches any characters and "?" any one
+// character. Other characters, such as "[" and "\", match themselves.
+func matchWildcard(pattern, text string) bool {
+	star, starText := -1, 0 // the last "*" of the pattern and where the text it matches ends
+	for p, t := 0, 0; t < len(text) || p < len(pattern); {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, starText = p, t
+				p++
+				continue
+			case '?':
+				if t < len(text) {
+					_, size := utf8.DecodeRuneInString(text[t:])
+					p, t = p+1, t+size
+					continue
+				}
+			default:
+				if t < len(text) && text[t] == pattern[p] {
+					p, t = p+1, t+1
+					continue
+				}
+			}
+		}
+		if star < 0 || starText >= len(text) {
+			return false
+		}
+		// Let the last "*" match one more character and try again from there.
+		_, size := utf8.DecodeRuneInString(text[starText:])
+		starText += size
+		p, t = star+1, starText
+	}
+
+	return true
+}
+
+// String returns the expression the Filter was parsed from.
+func (f Filter) String() string {
+	return f.text
+}
+
+// MarshalText writes the Filter as its expression, in the config file and in JSON.
+func (f Filter) MarshalText() ([]byte, error) {
+	return []byte(f.text), nil
+}
+
+// UnmarshalText parses a filter expression.
+func (f *Filter) UnmarshalText(text []byte) error {
+	parsed, err := ParseFilter(string(text))
+	if err != nil {
+		return err
+	}
+	*f = parsed
+	return nil
+}
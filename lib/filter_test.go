@@ -0,0 +1,127 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var keyboard = Device{
+	Path: []int{1, 2}, Name: "Logitech, Inc. Keyboard K120", VendorID: "046d", ProductID: "c31c", Speed: "1.5",
+	Bus: 3, DevNum: 4, State: StateAdded, Class: "per-interface", MaxPower: 90, VendorName: "Logitech, Inc.",
+	Interfaces: []Interface{{Number: 0, Class: "hid", SubClass: 1, Protocol: 1, Driver: "usbhid"}},
+}
+
+func TestParseFilter_Matches(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`vid:046d class:hid speed<480 bus:3 name~"keyboard" state:added port:3-1.*`, true},
+		{"", true},
+		{"VID:046D pid:C31C", true},
+		{"046d:c31c", true},
+		{"046d:c31d", false},
+		{"keyboard", true},
+		{"k120 046d", true},
+		{"mouse", false},
+		{`name~"keyboard k120"`, true},
+		{`name:"logitech, inc. keyboard k120"`, true},
+		{"name:logitech*", true},
+		{"name:keyboard", false},
+		{"vendor:logitech*", true},
+		{"class:03", true},
+		{"class:storage", false},
+		{"state:normal", false},
+		{"name:*k1?0", true},
+		{"name:*k1?", false},
+		{"port:3-1.2", true},
+		{"port:3-[12].?", true},
+		{"port:3-2*", false},
+		{"port~-1.", true},
+		{"speed:low", true},
+		{"speed<=1.5 speed>=1.5", true},
+		{"speed>12", false},
+		{"bus>2 bus<4", true},
+		{"devnum:5", false},
+		{"power>=100", false},
+		{"ports:0", true},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expression)
+		require.NoError(t, err, test.expression)
+		assert.Equal(t, test.want, filter.Matches(keyboard), test.expression)
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       error
+		column     int
+	}{
+		{`name~"keyboard`, ErrFilterSyntax, 1},
+		{"vid:046d pid:", ErrFilterSyntax, 10},
+		{"vid:046d  vendorid:046d", ErrUnknownFilterKey, 11},
+		{"name<keyboard", ErrFilterOperator, 1},
+		{"class~hid", ErrFilterOperator, 1},
+		{"bus~3", ErrFilterOperator, 1},
+		{"bus:three", ErrFilterValue, 1},
+		{"speed<fast", ErrFilterValue, 1},
+		{"state:plugged", ErrFilterValue, 1},
+	}
+	for _, test := range tests {
+		_, err := ParseFilter(test.expression)
+		require.ErrorIs(t, err, test.want, test.expression)
+
+		var filterErr *FilterError
+		require.ErrorAs(t, err, &filterErr)
+		assert.Equal(t, test.column, filterErr.Column, test.expression)
+	}
+}
+
+func TestFilter_TextWildcards(t *testing.T) {
+	tests := []struct {
+		expression string
+		device     Device
+		want       bool
+	}{
+		{"name:*hub*", Device{Name: "USB2.0/3.0 Hub"}, true},
+		{"name:usb*/*hub", Device{Name: "USB2.0/3.0 Hub"}, true},
+		{"name:*2.0?3.0*", Device{Name: "USB2.0/3.0 Hub"}, true},
+		{"name:*hub", Device{Name: "USB2.0/3.0 Hub Controller"}, false},
+		{`name:*[v2]*`, Device{Name: "Flash Drive [v2]"}, true},
+		{`name:*[v2]`, Device{Name: "Flash Drive v"}, false},
+		{`name:"c:\\tools*"`, Device{Name: `C:\Tools Dongle`}, true},
+		{`name:c:\tools*`, Device{Name: `C:\Tools Dongle`}, true},
+		{"name:*ünïcode?", Device{Name: "Ünïcode™"}, true},
+		{"serial:**", Device{}, true},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expression)
+		require.NoError(t, err, test.expression)
+		assert.Equal(t, test.want, filter.Matches(test.device), test.expression)
+	}
+}
+
+func TestFilter_UnknownSpeed(t *testing.T) {
+	filter, err := ParseFilter("speed<480")
+	require.NoError(t, err)
+
+	assert.False(t, filter.Matches(Device{Speed: ""}), "devices of unknown speed should not compare")
+}
+
+func TestFilter_String(t *testing.T) {
+	filter, err := ParseFilter(`  name~"usb hub" bus:1 `)
+	require.NoError(t, err)
+	assert.Equal(t, `name~"usb hub" bus:1`, filter.String())
+	assert.True(t, Filter{}.IsZero())
+	assert.False(t, filter.IsZero())
+
+	text, err := filter.MarshalText()
+	require.NoError(t, err)
+	var parsed Filter
+	require.NoError(t, parsed.UnmarshalText(text))
+	assert.Equal(t, filter, parsed)
+}
@@ -5,12 +5,12 @@ go 1.25.0
 require (
 	github.com/google/gousb v1.1.3
 	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 require (
 	github.com/google/gousb v1.1.3
@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/google/gousb"
+)
+
+// These errors are the kinds of PollError, telling why the Devices could not be read.
+var (
+	// ErrPermissionDenied means the user may not read the USB devices, e.g. for lack of a udev rule.
+	ErrPermissionDenied = errors.New("permission denied reading USB devices")
+	// ErrLibusbUnavailable means libusb could not be initialised, e.g. because it is not installed or the
+	// machine has no USB support.
+	ErrLibusbUnavailable = errors.New("libusb is unavailable")
+	// ErrUdevFailure means the details of the Devices could not be read from udev.
+	ErrUdevFailure = errors.New("reading udev failed")
+	// ErrEnumerationFailed means libusb failed to list the Devices for another reason.
+	ErrEnumerationFailed = errors.New("enumerating USB devices failed")
+
+	// ErrAlreadyStarted is returned by Init when the monitoring was started and not stopped.
+	ErrAlreadyStarted = errors.New("the monitoring is already started")
+	// ErrStopped is returned by Refresh when Stop is called before the poll goroutine of Init refreshed.
+	ErrStopped = errors.New("the monitoring was stopped")
+)
+
+// A PollError explains why a poll of the Devices failed. errors.Is matches both its Kind, one of
+// ErrPermissionDenied, ErrLibusbUnavailable, ErrUdevFailure and ErrEnumerationFailed, and the error causing it.
+type PollError struct {
+	Kind error
+	Err  error
+}
+
+func (e *PollError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *PollError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// usbError wraps an error of libusb in a PollError of the given kind, unless it is about permissions.
+func usbError(kind, err error) *PollError {
+	var libusbErr gousb.Error
+	if (errors.As(err, &libusbErr) && libusbErr == gousb.ErrorAccess) || errors.Is(err, fs.ErrPermission) {
+		kind = ErrPermissionDenied
+	}
+
+	return &PollError{Kind: kind, Err: err}
+}
+
+// A HealthState summarises whether the monitoring started by Init works.
+type HealthState string
+
+// These constants represent the HealthState of the monitoring.
+const (
+	HealthStarting HealthState = "starting" // the first poll has not completed
+	HealthOK       HealthState = "ok"
+	HealthFailing  HealthState = "failing" // polls fail and are retried with a growing delay
+	HealthStopped  HealthState = "stopped" // Init was not called, or Stop was
+)
+
+// A HealthStatus describes the state of the monitoring, and why it fails if it does.
+type HealthStatus struct {
+	State     HealthState `json:"state"`
+	Err       error       `json:"-"`                  // the error of the last poll while failing
+	Error     string      `json:"error,omitempty"`    // the message of Err
+	Failures  int         `json:"failures,omitempty"` // polls failed in a row
+	LastPoll  time.Time   `json:"lastPoll,omitzero"`  // time of the last successful poll
+	NextRetry time.Time   `json:"nextRetry,omitzero"` // when a failing poll is retried
+}
+
+var (
+	health     = HealthStatus{State: HealthStopped}
+	healthLock sync.Mutex
+)
+
+// Health returns the state of the monitoring started by Init.
+func Health() HealthStatus {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	return health
+}
+
+// setHealthState changes the state of the monitoring, keeping the time of the last successful poll.
+func setHealthState(state HealthState) {
+	healthLock.Lock()
+	health = HealthStatus{State: state, LastPoll: health.LastPoll}
+	healthLock.Unlock()
+}
+
+// recordPollResult updates the health with the outcome of a poll, retried after delay if it failed.
+func recordPollResult(err error, delay time.Duration) {
+	healthLock.Lock()
+	defer healthLock.Unlock()
+
+	if err == nil {
+		health = HealthStatus{State: HealthOK, LastPoll: time.Now()}
+		return
+	}
+	health = HealthStatus{
+		State:     HealthFailing,
+		Err:       err,
+		Error:     err.Error(),
+		Failures:  health.Failures + 1,
+		LastPoll:  health.LastPoll,
+		NextRetry: time.Now().Add(delay),
+	}
+}
+
+// retryDelay returns how long to wait before the next poll after failures in a row: doubling from
+// minRetryDelay up to maxRetryDelay.
+func retryDelay(failures int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
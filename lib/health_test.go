@@ -0,0 +1,98 @@
+package lib
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/gousb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastPolling shortens the intervals of Init for a test.
+func fastPolling(t *testing.T) {
+	pollInterval, minRetryDelay, maxRetryDelay = time.Millisecond, time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() {
+		pollInterval, minRetryDelay, maxRetryDelay = 250*time.Millisecond, time.Second, 30*time.Second
+	})
+}
+
+// stopPolling stops the polling of Init and waits for it to end.
+func stopPolling(t *testing.T) {
+	Stop()
+	assert.Eventually(t, func() bool { return Health().State == HealthStopped }, 5*time.Second, time.Millisecond)
+}
+
+func TestInit_RecoversFromErrors(t *testing.T) {
+	fastPolling(t)
+	var lock sync.Mutex
+	failures := 3
+	pollDevices = func() (time.Time, []Device, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			return time.Now(), nil, &PollError{Kind: ErrPermissionDenied, Err: gousb.ErrorAccess}
+		}
+		return time.Now(), []Device{device4, device1}, nil
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+
+	updates := make(chan []Device, 100)
+	devices, err := Init(func(devices []Device) { updates <- devices })
+	assert.Nil(t, devices)
+	require.ErrorIs(t, err, ErrPermissionDenied)
+	assert.ErrorIs(t, err, gousb.ErrorAccess)
+	assert.Equal(t, HealthFailing, Health().State)
+
+	_, err = Init(func([]Device) {})
+	assert.ErrorIs(t, err, ErrAlreadyStarted)
+
+	for devices == nil {
+		devices = nextUpdate(t, updates)
+	}
+	assert.Len(t, devices, 2)
+	assert.Equal(t, HealthOK, Health().State)
+	assert.Zero(t, Health().Failures)
+
+	stopPolling(t)
+}
+
+func TestInit_ReportsFailures(t *testing.T) {
+	fastPolling(t)
+	pollDevices = func() (time.Time, []Device, error) {
+		return time.Now(), nil, &PollError{Kind: ErrLibusbUnavailable, Err: errors.New("no USB support")}
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+
+	_, err := Init(func([]Device) {})
+	require.ErrorIs(t, err, ErrLibusbUnavailable)
+	t.Cleanup(func() { stopPolling(t) })
+
+	assert.Eventually(t, func() bool { return Health().Failures >= 3 }, 5*time.Second, time.Millisecond)
+	status := Health()
+	assert.Equal(t, HealthFailing, status.State)
+	assert.Equal(t, "libusb is unavailable: no USB support", status.Error)
+	assert.False(t, status.NextRetry.IsZero())
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Second, retryDelay(1))
+	assert.Equal(t, 2*time.Second, retryDelay(2))
+	assert.Equal(t, 4*time.Second, retryDelay(3))
+	assert.Equal(t, 30*time.Second, retryDelay(10))
+}
+
+func TestUSBError(t *testing.T) {
+	err := usbError(ErrEnumerationFailed, gousb.ErrorAccess)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.NotErrorIs(t, err, ErrEnumerationFailed)
+
+	err = usbError(ErrLibusbUnavailable, gousb.ErrorOther)
+	assert.ErrorIs(t, err, ErrLibusbUnavailable)
+	var pollErr *PollError
+	require.ErrorAs(t, err, &pollErr)
+	assert.Equal(t, gousb.ErrorOther, pollErr.Err)
+}
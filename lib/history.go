@@ -0,0 +1,92 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Sighting records when a Device was first and last seen on this machine.
+type Sighting struct {
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// History maps the identity of every Device seen on this machine to its Sighting. Devices are identified
+// by their serial number, or by their port when they have none.
+type History map[string]Sighting
+
+// DefaultHistoryPath returns the location of the history file in the user's config directory.
+func DefaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "usb-tree-history.json"
+	}
+
+	return filepath.Join(dir, "usb-tree", "history.json")
+}
+
+// LoadHistory reads a History from path. A missing file results in an empty History.
+func LoadHistory(path string) (History, error) {
+	history := History{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// SaveHistory writes the History to path as JSON, creating the parent directory if needed.
+func SaveHistory(path string, history History) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Record marks the Devices as seen at seenTime.
+func (h History) Record(devices []Device, seenTime time.Time) {
+	for _, device := range devices {
+		key := historyKey(device)
+		sighting, ok := h[key]
+		if !ok {
+			sighting.FirstSeen = seenTime
+		}
+		sighting.LastSeen = seenTime
+		h[key] = sighting
+	}
+}
+
+// Lookup returns the Sighting of the Device, if it was recorded.
+func (h History) Lookup(device Device) (Sighting, bool) {
+	sighting, ok := h[historyKey(device)]
+
+	return sighting, ok
+}
+
+// historyKey identifies a Device across reconnections: by serial number when it has one, so it is recognized
+// on any port, and by port otherwise.
+func historyKey(device Device) string {
+	if device.Serial != "" {
+		return device.VendorID + ":" + device.ProductID + ":" + device.Serial
+	}
+
+	return device.VendorID + ":" + device.ProductID + "@" + device.PortPath()
+}
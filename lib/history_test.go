@@ -0,0 +1,48 @@
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory_Record(t *testing.T) {
+	first := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	history := History{}
+	history.Record([]Device{probe, device1}, first)
+
+	moved := probe
+	moved.Path = []int{1}
+	history.Record([]Device{moved}, second)
+
+	sighting, ok := history.Lookup(probe)
+	require.True(t, ok)
+	assert.Equal(t, Sighting{FirstSeen: first, LastSeen: second}, sighting, "devices with a serial are recognized on any port")
+
+	sighting, ok = history.Lookup(device1)
+	require.True(t, ok)
+	assert.Equal(t, Sighting{FirstSeen: first, LastSeen: first}, sighting)
+
+	_, ok = history.Lookup(device2)
+	assert.False(t, ok)
+}
+
+func TestHistory_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usb-tree", "history.json")
+
+	history, err := LoadHistory(path)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	history.Record([]Device{probe}, time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC))
+	require.NoError(t, SaveHistory(path, history))
+
+	loaded, err := LoadHistory(path)
+	require.NoError(t, err)
+	assert.Equal(t, history, loaded)
+}
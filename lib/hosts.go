@@ -0,0 +1,219 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostStateInterval is how often a MultiSource checks whether the state of a connection changed.
+const hostStateInterval = time.Second
+
+// A HostStatus is the state of the connection to one host of a MultiSource.
+type HostStatus struct {
+	Host  string          `json:"host"`
+	State ConnectionState `json:"state"`
+}
+
+// A MultiSource is a Source combining the Devices and logs of several usb-tree daemons. Its Devices and log
+// entries are tagged with the Host they come from.
+type MultiSource struct {
+	clients []*Client
+
+	lock     sync.Mutex
+	devices  [][]Device // the Devices of each client
+	statuses []HostStatus
+	stop     chan struct{}
+}
+
+// ConnectAll returns a MultiSource following the daemons at addresses, which take the forms accepted by
+// Connect.
+func ConnectAll(addresses []string) *MultiSource {
+	m := &MultiSource{devices: make([][]Device, len(addresses))}
+	for _, address := range addresses {
+		m.clients = append(m.clients, Connect(address))
+	}
+
+	return m
+}
+
+// Start connects to every daemon and calls onUpdate with the Devices of all of them whenever those of one
+// change, or the state of a connection changes. Like Client.Start, it never fails.
+func (m *MultiSource) Start(onUpdate func([]Device)) error {
+	m.lock.Lock()
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.lock.Unlock()
+
+	for i, client := range m.clients {
+		_ = client.Start(func(devices []Device) {
+			m.lock.Lock()
+			m.devices[i] = devices
+			m.lock.Unlock()
+			onUpdate(m.combined())
+		})
+	}
+
+	go func() {
+		ticker := time.NewTicker(hostStateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				statuses := m.Hosts()
+				m.lock.Lock()
+				changed := !slices.Equal(statuses, m.statuses)
+				m.statuses = statuses
+				m.lock.Unlock()
+				if changed {
+					onUpdate(m.combined())
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop disconnects from every daemon.
+func (m *MultiSource) Stop() {
+	m.lock.Lock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	m.lock.Unlock()
+
+	for _, client := range m.clients {
+		client.Stop()
+	}
+}
+
+// Refresh asks every daemon to read its Devices again.
+func (m *MultiSource) Refresh() time.Time {
+	for _, client := range m.clients {
+		client.Refresh()
+	}
+
+	return time.Now()
+}
+
+// Log returns the log entries of every daemon, oldest first.
+func (m *MultiSource) Log() []Log {
+	var logs []Log
+	hosts := m.hostNames()
+	for i, client := range m.clients {
+		for _, log := range client.Log() {
+			log.Host = hosts[i]
+			logs = append(logs, log)
+		}
+	}
+	slices.SortStableFunc(logs, func(a, b Log) int { return a.Time.Compare(b.Time) })
+
+	return logs
+}
+
+// Hosts returns the state of the connection to every daemon, in the order they were given to ConnectAll.
+func (m *MultiSource) Hosts() []HostStatus {
+	statuses := make([]HostStatus, len(m.clients))
+	hosts := m.hostNames()
+	for i, client := range m.clients {
+		statuses[i] = HostStatus{Host: hosts[i], State: client.State()}
+	}
+
+	return statuses
+}
+
+// Health combines the Health of the connections to the daemons: failing when one of them fails, naming the
+// hosts that do, starting while one is connecting, and ok when all are connected.
+func (m *MultiSource) Health() HealthStatus {
+	hosts := m.hostNames()
+	status := HealthStatus{State: HealthOK}
+	var errs []error
+	for i, client := range m.clients {
+		clientStatus := client.Health()
+		switch clientStatus.State {
+		case HealthFailing:
+			status.State = HealthFailing
+			errs = append(errs, fmt.Errorf("%s: %w", hosts[i], clientStatus.Err))
+			status.Failures += clientStatus.Failures
+		case HealthStarting, HealthStopped:
+			if status.State == HealthOK {
+				status.State = clientStatus.State
+			}
+		}
+		if clientStatus.LastPoll.After(status.LastPoll) {
+			status.LastPoll = clientStatus.LastPoll
+		}
+	}
+	if status.Err = errors.Join(errs...); status.Err != nil {
+		status.Error = strings.ReplaceAll(status.Err.Error(), "\n", "; ")
+	}
+
+	return status
+}
+
+// hostNames returns the name of the host of every daemon. Daemons on machines with the same name, such as
+// several on one machine, are told apart by their address.
+func (m *MultiSource) hostNames() []string {
+	names := make([]string, len(m.clients))
+	count := map[string]int{}
+	for i, client := range m.clients {
+		names[i] = client.Host()
+		count[names[i]]++
+	}
+	for i, client := range m.clients {
+		if count[names[i]] > 1 {
+			names[i] += " (" + client.address + ")"
+		}
+	}
+
+	return names
+}
+
+// combined returns the Devices of every daemon tagged with their Host.
+func (m *MultiSource) combined() []Device {
+	hosts := m.hostNames()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	devices := []Device{}
+	for i := range m.clients {
+		for _, device := range m.devices[i] {
+			device.Host = hosts[i]
+			devices = append(devices, device)
+		}
+	}
+
+	return devices
+}
+
+// BuildHostTree builds a tree with a node for each of the hosts, in order, above the tree of its Devices. The
+// nodes of the hosts are Devices for which IsHost is true.
+func BuildHostTree(hosts []string, devices []Device) []*TreeNode {
+	roots := make([]*TreeNode, len(hosts))
+	for i, host := range hosts {
+		var hostDevices []Device
+		for _, device := range devices {
+			if device.Host == host {
+				hostDevices = append(hostDevices, device)
+			}
+		}
+		roots[i] = &TreeNode{
+			Device:   Device{Name: host, Host: host, State: StateNormal},
+			Children: BuildDeviceTree(hostDevices),
+		}
+	}
+
+	return roots
+}
+
+// IsHost reports whether the Device is the node of a host in a tree built by BuildHostTree.
+func (d *Device) IsHost() bool {
+	return d.Host != "" && d.Bus == 0 && d.VendorID == ""
+}
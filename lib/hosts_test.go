@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiSource(t *testing.T) {
+	logs = nil
+	addErrorLog("shared log entry", time.Now(), StateError)
+	lab1, lab2 := NewServer(), NewServer()
+	lab1.update([]Device{device4})
+	lab2.update([]Device{device4, device1})
+	server1, server2 := httptest.NewServer(lab1), httptest.NewServer(lab2)
+	defer server1.Close()
+	defer server2.Close()
+
+	updates := make(chan []Device, 10)
+	source := ConnectAll([]string{server1.URL, server2.URL})
+	source.Start(func(devices []Device) { updates <- devices })
+	defer source.Stop()
+
+	var devices []Device
+	for len(devices) < 3 {
+		devices = nextUpdate(t, updates)
+	}
+
+	hosts := source.Hosts()
+	require.Len(t, hosts, 2)
+	assert.Equal(t, ConnectionConnected, hosts[0].State)
+	assert.True(t, strings.HasSuffix(hosts[0].Host, "("+server1.URL+")"),
+		"daemons on the same machine are told apart by address, got %q", hosts[0].Host)
+
+	roots := BuildHostTree([]string{hosts[0].Host, hosts[1].Host}, devices)
+	require.Len(t, roots, 2)
+	assert.True(t, roots[0].IsHost())
+	require.Len(t, roots[1].Children, 1)
+	assert.Equal(t, "Root", roots[1].Children[0].Name)
+	require.Len(t, roots[1].Children[0].Children, 1)
+	assert.Equal(t, "Device 1", roots[1].Children[0].Children[0].Name)
+	assert.NotEqual(t, roots[0].Children[0].Key(), roots[1].Children[0].Key(), "keys must differ between hosts")
+
+	log := source.Log()
+	require.Len(t, log, 2)
+	assert.Equal(t, hosts[0].Host, log[0].Host)
+	assert.Equal(t, hosts[1].Host, log[1].Host)
+}
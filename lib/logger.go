@@ -0,0 +1,28 @@
+package lib
+
+import (
+	"log/slog"
+	"sync"
+)
+
+var (
+	logger     = slog.New(slog.DiscardHandler)
+	loggerLock sync.RWMutex
+)
+
+// SetLogger sets where lib reports what it does internally, such as polls, cache misses and errors, to
+// diagnose problems. Nothing is reported until it is called. Unlike the log returned by GetLog, this is meant
+// for developers rather than users.
+func SetLogger(l *slog.Logger) {
+	loggerLock.Lock()
+	logger = l
+	loggerLock.Unlock()
+}
+
+// getLogger returns the logger set by SetLogger.
+func getLogger() *slog.Logger {
+	loggerLock.RLock()
+	defer loggerLock.RUnlock()
+
+	return logger
+}
@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(slog.New(slog.DiscardHandler))
+
+	fakeRefresh([]Device{device1})
+	deviceDiff([]Device{device1, device2}, time.Now())
+
+	assert.Contains(t, buf.String(), `level=DEBUG msg="devices changed" devices=2 events=1 initial=false`)
+	assert.Contains(t, buf.String(), `level=INFO msg="device event" type=added device=`+device2.Key())
+}
@@ -0,0 +1,107 @@
+package lib
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A DeviceMatcher selects Devices by their identifying attributes. Empty fields match any value,
+// but a matcher with every field empty matches nothing. Port accepts shell wildcards and Class accepts
+// a class name such as "hid" or a hex class code such as "03"; it also matches the Device interfaces.
+type DeviceMatcher struct {
+	VendorID  string   `yaml:"vid,omitempty" json:"vid,omitempty"`
+	ProductID string   `yaml:"pid,omitempty" json:"pid,omitempty"`
+	Serial    string   `yaml:"serial,omitempty" json:"serial,omitempty"`
+	Port      string   `yaml:"port,omitempty" json:"port,omitempty"`
+	Class     string   `yaml:"class,omitempty" json:"class,omitempty"`
+	State     LogState `yaml:"state,omitempty" json:"state,omitempty"`
+}
+
+// IsZero reports whether the matcher has no fields set.
+func (m DeviceMatcher) IsZero() bool {
+	return m == DeviceMatcher{}
+}
+
+// Matches reports whether the Device satisfies every field set on the matcher.
+func (m DeviceMatcher) Matches(device Device) bool {
+	if m.IsZero() {
+		return false
+	}
+	if m.VendorID != "" && !strings.EqualFold(m.VendorID, device.VendorID) {
+		return false
+	}
+	if m.ProductID != "" && !strings.EqualFold(m.ProductID, device.ProductID) {
+		return false
+	}
+	if m.Serial != "" && m.Serial != device.Serial {
+		return false
+	}
+	if m.Port != "" && !matchPort(m.Port, device.PortPath()) {
+		return false
+	}
+	if m.Class != "" && !device.HasClass(m.Class) {
+		return false
+	}
+	if m.State != "" && m.State != device.State {
+		return false
+	}
+
+	return true
+}
+
+// String returns the matcher in a compact human-readable form.
+func (m DeviceMatcher) String() string {
+	var parts []string
+	if m.VendorID != "" || m.ProductID != "" {
+		parts = append(parts, fmt.Sprintf("%s:%s", orAny(m.VendorID), orAny(m.ProductID)))
+	}
+	if m.Serial != "" {
+		parts = append(parts, "serial "+m.Serial)
+	}
+	if m.Port != "" {
+		parts = append(parts, "port "+m.Port)
+	}
+	if m.Class != "" {
+		parts = append(parts, "class "+m.Class)
+	}
+	if m.State != "" {
+		parts = append(parts, "state "+string(m.State))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// matchPort reports whether a port path equals the pattern, which may use shell wildcards like "1-3.*".
+func matchPort(pattern, port string) bool {
+	if pattern == port {
+		return true
+	}
+	matched, err := path.Match(pattern, port)
+	return err == nil && matched
+}
+
+func orAny(s string) string {
+	if s == "" {
+		return "*"
+	}
+
+	return s
+}
+
+// MatcherFor returns the narrowest matcher identifying the Device: its serial number when it has one,
+// otherwise the port it is plugged into.
+func MatcherFor(device Device) DeviceMatcher {
+	if device.Serial != "" {
+		return DeviceMatcher{VendorID: device.VendorID, ProductID: device.ProductID, Serial: device.Serial}
+	}
+
+	return DeviceMatcher{VendorID: device.VendorID, ProductID: device.ProductID, Port: device.PortPath()}
+}
+
+// isHexID reports whether s is a four digit hexadecimal vendor or product ID.
+func isHexID(s string) bool {
+	_, err := strconv.ParseUint(s, 16, 16)
+	return len(s) == 4 && err == nil
+}
@@ -0,0 +1,64 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceMatcher_Matches(t *testing.T) {
+	assert.True(t, DeviceMatcher{VendorID: "1366", ProductID: "0105"}.Matches(probe))
+	assert.True(t, DeviceMatcher{VendorID: "1366"}.Matches(probe))
+	assert.True(t, DeviceMatcher{Serial: "000123"}.Matches(probe))
+	assert.True(t, DeviceMatcher{Port: "1-3.2"}.Matches(probe))
+	assert.True(t, DeviceMatcher{VendorID: "1366", Serial: "000123", Port: "1-3.2"}.Matches(probe))
+
+	assert.False(t, DeviceMatcher{}.Matches(probe), "an empty matcher should match nothing")
+	assert.False(t, DeviceMatcher{VendorID: "1366", ProductID: "0101"}.Matches(probe))
+	assert.False(t, DeviceMatcher{Serial: "000124"}.Matches(probe))
+	assert.False(t, DeviceMatcher{Port: "1-3"}.Matches(probe))
+}
+
+func TestMatcherFor(t *testing.T) {
+	assert.Equal(t, DeviceMatcher{VendorID: "1366", ProductID: "0105", Serial: "000123"}, MatcherFor(probe))
+
+	noSerial := probe
+	noSerial.Serial = ""
+	assert.Equal(t, DeviceMatcher{VendorID: "1366", ProductID: "0105", Port: "1-3.2"}, MatcherFor(noSerial))
+}
+
+func TestDeviceMatcher_PortPattern(t *testing.T) {
+	assert.True(t, DeviceMatcher{Port: "1-3.*"}.Matches(probe))
+	assert.True(t, DeviceMatcher{Port: "1-*"}.Matches(probe))
+	assert.False(t, DeviceMatcher{Port: "2-*"}.Matches(probe))
+	assert.False(t, DeviceMatcher{Port: "1-3.["}.Matches(probe), "a malformed pattern should not match")
+}
+
+func TestDeviceMatcher_Class(t *testing.T) {
+	keyboard := Device{
+		Path: []int{1}, Bus: 1, VendorID: "046d", ProductID: "c31c", Class: "per-interface",
+		Interfaces: []Interface{{Number: 0, Class: "hid", SubClass: 1, Protocol: 1}},
+	}
+
+	assert.True(t, DeviceMatcher{Class: "hid"}.Matches(keyboard), "interface classes should match")
+	assert.True(t, DeviceMatcher{Class: "03"}.Matches(keyboard))
+	assert.True(t, DeviceMatcher{Class: "0x03"}.Matches(keyboard))
+	assert.True(t, DeviceMatcher{Class: "HID"}.Matches(keyboard))
+	assert.False(t, DeviceMatcher{Class: "storage"}.Matches(keyboard))
+	assert.True(t, DeviceMatcher{Class: "hub"}.Matches(Device{Class: "hub"}))
+}
+
+func TestNormalizeClass(t *testing.T) {
+	assert.Equal(t, "hid", normalizeClass("03"))
+	assert.Equal(t, "storage", normalizeClass("0x8"))
+	assert.Equal(t, "audio-video", normalizeClass("10"), "two digit codes are hex")
+	assert.Equal(t, "8", normalizeClass("8"), "decimal codes are not converted")
+}
+
+func TestDeviceMatcher_State(t *testing.T) {
+	removed := probe
+	removed.State = StateRemoved
+
+	assert.True(t, DeviceMatcher{VendorID: "1366", State: StateRemoved}.Matches(removed))
+	assert.False(t, DeviceMatcher{VendorID: "1366", State: StateAdded}.Matches(removed))
+}
@@ -0,0 +1,176 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the poll duration histograms.
+var durationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// histogram counts observations in cumulative buckets, as exposed by Prometheus.
+type histogram struct {
+	counts []uint64 // observations up to each of durationBuckets
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(durationBuckets))
+	}
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// eventCounterKey identifies a counter of Events by type and Device identity.
+type eventCounterKey struct {
+	eventType EventType
+	vendorID  string
+	productID string
+	serial    string
+}
+
+var (
+	pollDurations        histogram
+	enumerationDurations histogram
+	eventCounts          = map[eventCounterKey]uint64{}
+	errorCount           uint64
+	metricsLock          sync.Mutex
+)
+
+// recordPoll records how long a poll took in total, and how much of it was spent enumerating with libusb
+// rather than reading details from the system.
+func recordPoll(poll, enumeration time.Duration) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	pollDurations.observe(poll.Seconds())
+	enumerationDurations.observe(enumeration.Seconds())
+}
+
+func countEvent(event Event) {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	eventCounts[eventCounterKey{
+		eventType: event.Type,
+		vendorID:  event.Device.VendorID,
+		productID: event.Device.ProductID,
+		serial:    event.Device.Serial,
+	}]++
+}
+
+func countError() {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	errorCount++
+}
+
+// WriteMetrics writes metrics about the Devices, the Events and the polls in the Prometheus text format.
+// Removed Devices still shown by the UIs are not counted.
+func WriteMetrics(w io.Writer, devices []Device) error {
+	var connected []Device
+	for _, device := range devices {
+		if device.State != StateRemoved {
+			connected = append(connected, device)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	writeDeviceGauge(out, "usb_tree_devices_by_bus", "Connected USB devices per bus.", "bus", connected,
+		func(d Device) string { return strconv.Itoa(d.Bus) })
+	writeDeviceGauge(out, "usb_tree_devices_by_class", "Connected USB devices per device class.", "class", connected,
+		func(d Device) string { return d.Class })
+	writeDeviceGauge(out, "usb_tree_devices_by_speed", "Connected USB devices per speed in Mbps.", "speed", connected,
+		func(d Device) string { return d.Speed })
+
+	writeHeader(out, "usb_tree_device_info", "gauge", "Connected USB devices, always 1; alert on its absence to notice a device leaving.")
+	for _, device := range connected {
+		fmt.Fprintf(out, "usb_tree_device_info%s 1\n", labels(
+			"vid", device.VendorID, "pid", device.ProductID, "serial", device.Serial,
+			"port", device.PortPath(), "name", strings.TrimSpace(device.DisplayName())))
+	}
+
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+
+	writeHeader(out, "usb_tree_device_events_total", "counter", "Device events by type and device identity.")
+	keys := slices.SortedFunc(maps.Keys(eventCounts), func(a, b eventCounterKey) int {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	})
+	for _, key := range keys {
+		fmt.Fprintf(out, "usb_tree_device_events_total%s %d\n", labels(
+			"type", string(key.eventType), "vid", key.vendorID, "pid", key.productID, "serial", key.serial), eventCounts[key])
+	}
+
+	writeHeader(out, "usb_tree_errors_total", "counter", "Errors reading the USB devices.")
+	fmt.Fprintf(out, "usb_tree_errors_total %d\n", errorCount)
+
+	writeHistogram(out, "usb_tree_poll_duration_seconds", "Duration of each poll of the USB devices.", pollDurations)
+	writeHistogram(out, "usb_tree_enumeration_duration_seconds",
+		"Time each poll spent enumerating the USB devices, without reading their details from the system.", enumerationDurations)
+
+	return out.Flush()
+}
+
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeDeviceGauge writes a gauge counting the Devices per value of one label.
+func writeDeviceGauge(w io.Writer, name, help, label string, devices []Device, value func(Device) string) {
+	counts := map[string]int{}
+	for _, device := range devices {
+		counts[value(device)]++
+	}
+
+	writeHeader(w, name, "gauge", help)
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "%s%s %d\n", name, labels(label, key), counts[key])
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, h histogram) {
+	writeHeader(w, name, "histogram", help)
+	for i, bound := range durationBuckets {
+		var count uint64
+		if h.counts != nil {
+			count = h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels("le", strconv.FormatFloat(bound, 'g', -1, 64)), count)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, labels("le", "+Inf"), h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// labels formats name and value pairs as a Prometheus label set.
+func labels(pairs ...string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `%s="%s"`, pairs[i], escape.Replace(pairs[i+1]))
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
@@ -0,0 +1,59 @@
+package lib
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	removed := device3
+	removed.State = StateRemoved
+	hid := device2
+	hid.Class = "hid"
+	hid.Bus = 2
+
+	countEvent(Event{Type: EventAdded, Device: probe})
+	countEvent(Event{Type: EventAdded, Device: probe})
+	recordPoll(30*time.Millisecond, 4*time.Millisecond)
+
+	var sb strings.Builder
+	require.NoError(t, WriteMetrics(&sb, []Device{device4, device1, hid, removed}))
+	metrics := sb.String()
+
+	assert.Contains(t, metrics, "# TYPE usb_tree_devices_by_bus gauge\n")
+	assert.Contains(t, metrics, "usb_tree_devices_by_bus{bus=\"1\"} 2\n", "removed devices are not counted")
+	assert.Contains(t, metrics, "usb_tree_devices_by_bus{bus=\"2\"} 1\n")
+	assert.Contains(t, metrics, "usb_tree_devices_by_class{class=\"hid\"} 1\n")
+	assert.Contains(t, metrics, "usb_tree_devices_by_speed{speed=\"High\"} 3\n")
+	assert.Contains(t, metrics, `usb_tree_device_info{vid="0001",pid="0010",serial="",port="1-1",name="Device 1"} 1`)
+	assert.Contains(t, metrics, `usb_tree_device_events_total{type="added",vid="1366",pid="0105",serial="000123"} `)
+	assert.Contains(t, metrics, "usb_tree_poll_duration_seconds_bucket{le=\"0.05\"} ")
+	assert.Contains(t, metrics, "usb_tree_enumeration_duration_seconds_count ")
+}
+
+func TestLabels_Escaping(t *testing.T) {
+	assert.Equal(t, `{name="Say \"hi\"\\\n"}`, labels("name", "Say \"hi\"\\\n"))
+}
+
+func TestServer_Metrics(t *testing.T) {
+	server := NewServer()
+	server.update([]Device{device4})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "usb_tree_devices_by_bus{bus=\"1\"} 1\n")
+}
@@ -0,0 +1,513 @@
+package lib
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMQTTPrefix is the first level of the topics of an MQTTPublisher unless MQTTOptions say otherwise.
+const DefaultMQTTPrefix = "usb-tree"
+
+const (
+	mqttRetryDelay = 2 * time.Second  // how long an MQTTPublisher waits before reconnecting to the broker
+	mqttKeepAlive  = 30 * time.Second // how long the broker waits for an idle MQTTPublisher before dropping it
+	mqttTimeout    = 10 * time.Second // bounds connecting and each exchange with the broker
+	mqttBuffer     = 1024             // messages kept while the broker is unreachable, the oldest are dropped beyond
+)
+
+// MQTT control packet types of MQTT 3.1.1 used by an MQTTPublisher.
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+// mqttConnackErrors explains the return codes of a refused connection.
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// MQTTOptions configure the connection of an MQTTPublisher to its broker.
+type MQTTOptions struct {
+	// Broker is the URL of the broker: mqtt:// or tcp:// for plain connections, mqtts://, ssl:// or tls:// for
+	// TLS. The port defaults to 1883, or 8883 with TLS. The URL may hold the credentials.
+	Broker   string
+	Username string
+	Password string
+
+	// TLS configures connections to brokers with TLS URLs, e.g. with a private CA or a client certificate. Nil
+	// verifies the broker against the system roots.
+	TLS *tls.Config
+
+	// Prefix is the first level of every topic, DefaultMQTTPrefix when empty.
+	Prefix string
+	// Hostname is the second level of every topic, the name of this machine when empty.
+	Hostname string
+	// ClientID identifies the connection to the broker, "usb-tree-" followed by the Hostname when empty.
+	ClientID string
+}
+
+// mqttMessage is one message waiting to be published.
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// An MQTTPublisher publishes the Devices and Events to an MQTT broker with QoS 1:
+//
+//	<prefix>/<host>/status         "online", or "offline" once disconnected; retained
+//	<prefix>/<host>/<port>/state   the Device on the port, e.g. "1-3.2", as JSON; retained, and cleared when
+//	                               the Device is removed
+//	<prefix>/<host>/<port>/event   the Events of the Device on the port as JSON, as written by `usb-tree events`
+//
+// It keeps messages while the broker cannot be reached and reconnects until closed.
+type MQTTPublisher struct {
+	options MQTTOptions
+	address string // host and port of the broker
+	useTLS  bool
+
+	lock     sync.Mutex
+	pending  []mqttMessage
+	states   map[string][]byte // the payload of every state topic published, by topic
+	dropping bool              // whether messages were dropped since the last one was delivered
+	packetID uint16
+	closed   bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewMQTTPublisher returns an MQTTPublisher connecting to the broker in the background. It fails only for
+// invalid options; connection failures are added to the log.
+func NewMQTTPublisher(options MQTTOptions) (*MQTTPublisher, error) {
+	broker, err := url.Parse(options.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MQTT broker %q: %w", options.Broker, err)
+	}
+
+	p := &MQTTPublisher{
+		options: options,
+		states:  map[string][]byte{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	port := "1883"
+	switch broker.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl", "tls":
+		p.useTLS = true
+		port = "8883"
+	default:
+		return nil, fmt.Errorf("invalid MQTT broker %q, expected an mqtt:// or mqtts:// URL", options.Broker)
+	}
+	if broker.Hostname() == "" {
+		return nil, fmt.Errorf("invalid MQTT broker %q, the host is missing", options.Broker)
+	}
+	if broker.Port() != "" {
+		port = broker.Port()
+	}
+	p.address = net.JoinHostPort(broker.Hostname(), port)
+
+	if p.options.Username == "" && broker.User != nil {
+		p.options.Username = broker.User.Username()
+		p.options.Password, _ = broker.User.Password()
+	}
+	if p.options.Password != "" && p.options.Username == "" {
+		return nil, errors.New("an MQTT password requires a user name")
+	}
+	if p.options.Prefix == "" {
+		p.options.Prefix = DefaultMQTTPrefix
+	}
+	if p.options.Hostname == "" {
+		p.options.Hostname, _ = os.Hostname()
+	}
+	if p.options.ClientID == "" {
+		p.options.ClientID = "usb-tree-" + p.options.Hostname
+	}
+
+	go p.run()
+
+	return p, nil
+}
+
+// Send publishes the Event on the event topic of its Device.
+func (p *MQTTPublisher) Send(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.enqueue(mqttMessage{topic: p.deviceTopic(event.Device, "event"), payload: payload})
+}
+
+// Update publishes the state topics of the Devices that were added or changed since the last call, and clears
+// those of the Devices no longer connected. Nil Devices, for a failed poll, are ignored.
+func (p *MQTTPublisher) Update(devices []Device) error {
+	if devices == nil {
+		return nil
+	}
+
+	states := map[string][]byte{}
+	for _, device := range devices {
+		if device.State == StateRemoved {
+			continue
+		}
+		payload, err := json.Marshal(device)
+		if err != nil {
+			return err
+		}
+		states[p.deviceTopic(device, "state")] = payload
+	}
+
+	p.lock.Lock()
+	var messages []mqttMessage
+	for topic, payload := range states {
+		if published, ok := p.states[topic]; !ok || string(published) != string(payload) {
+			messages = append(messages, mqttMessage{topic: topic, payload: payload, retain: true})
+		}
+	}
+	for topic := range p.states {
+		if _, ok := states[topic]; !ok {
+			// An empty retained message removes the retained state from the broker.
+			messages = append(messages, mqttMessage{topic: topic, retain: true})
+		}
+	}
+	p.states = states
+	p.lock.Unlock()
+
+	return p.enqueue(messages...)
+}
+
+// Close publishes the messages still waiting if the broker is connected, marks the host offline and
+// disconnects.
+func (p *MQTTPublisher) Close() error {
+	p.lock.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.stop)
+	}
+	p.lock.Unlock()
+
+	<-p.done
+
+	return nil
+}
+
+func (p *MQTTPublisher) topic(levels ...string) string {
+	topic := []string{p.options.Prefix, mqttLevel(p.options.Hostname)}
+	for _, level := range levels {
+		topic = append(topic, mqttLevel(level))
+	}
+
+	return strings.Join(topic, "/")
+}
+
+func (p *MQTTPublisher) deviceTopic(device Device, kind string) string {
+	return p.topic(device.PortPath(), kind)
+}
+
+// mqttLevel replaces the characters with a meaning in topics by underscores.
+func mqttLevel(level string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(level)
+}
+
+// enqueue adds messages to those waiting to be published, dropping the oldest when too many are waiting.
+func (p *MQTTPublisher) enqueue(messages ...mqttMessage) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return errors.New("the MQTT publisher is closed")
+	}
+	p.pending = append(p.pending, messages...)
+	if overflow := len(p.pending) - mqttBuffer; overflow > 0 {
+		p.pending = p.pending[overflow:]
+		if !p.dropping {
+			p.dropping = true
+			addErrorLog(fmt.Sprintf("MQTT broker %s is not keeping up, dropping messages", p.address), time.Now(), StateError)
+		}
+	}
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// next removes the oldest waiting message.
+func (p *MQTTPublisher) next() (mqttMessage, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.pending) == 0 {
+		return mqttMessage{}, false
+	}
+	message := p.pending[0]
+	p.pending = p.pending[1:]
+
+	return message, true
+}
+
+// requeue puts back a message that could not be delivered, ahead of the others.
+func (p *MQTTPublisher) requeue(message mqttMessage) {
+	p.lock.Lock()
+	p.pending = append([]mqttMessage{message}, p.pending...)
+	p.lock.Unlock()
+}
+
+// run connects to the broker and publishes the waiting messages, reconnecting until closed.
+func (p *MQTTPublisher) run() {
+	defer close(p.done)
+
+	logged := false
+	for {
+		conn, reader, err := p.connect()
+		if err == nil {
+			getLogger().Info("connected to MQTT broker", "broker", p.address)
+			logged = false
+			if err = p.session(conn, reader); err == nil {
+				return
+			}
+		}
+		getLogger().Warn("MQTT broker unreachable", "broker", p.address, "error", err)
+		// Only the first failure of an outage is added to the log, not every attempt to reconnect.
+		if !logged {
+			logged = true
+			addErrorLog(fmt.Sprintf("MQTT broker %s: %v", p.address, err), time.Now(), StateError)
+		}
+
+		select {
+		case <-time.After(mqttRetryDelay):
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// connect opens a session with the broker. The broker marks the host offline if the connection is lost.
+func (p *MQTTPublisher) connect() (net.Conn, *bufio.Reader, error) {
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	var err error
+	if p.useTLS {
+		config := p.options.TLS
+		if config == nil {
+			config = &tls.Config{}
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", p.address, config)
+	} else {
+		conn, err = dialer.Dial("tcp", p.address)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Clean session, with a retained will of QoS 1.
+	flags := byte(0x02 | 0x04 | 0x08 | 0x20)
+	if p.options.Username != "" {
+		flags |= 0x80
+	}
+	if p.options.Password != "" {
+		flags |= 0x40
+	}
+	body := appendMQTTString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(mqttKeepAlive/time.Second))
+	body = appendMQTTString(body, p.options.ClientID)
+	body = appendMQTTString(body, p.topic("status"))
+	body = appendMQTTString(body, "offline")
+	if p.options.Username != "" {
+		body = appendMQTTString(body, p.options.Username)
+	}
+	if p.options.Password != "" {
+		body = appendMQTTString(body, p.options.Password)
+	}
+
+	reader := bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(mqttTimeout))
+	err = writeMQTTPacket(conn, mqttConnect, 0, body)
+	if err == nil {
+		var packetType byte
+		packetType, _, body, err = readMQTTPacket(reader)
+		switch {
+		case err != nil:
+		case packetType != mqttConnack || len(body) != 2:
+			err = fmt.Errorf("unexpected MQTT packet of type %d instead of CONNACK", packetType)
+		case body[1] != 0:
+			err = fmt.Errorf("connection refused: %s", mqttConnackErrors[body[1]])
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, reader, nil
+}
+
+// session publishes the waiting messages until the connection fails, or the MQTTPublisher is closed, which
+// returns nil.
+func (p *MQTTPublisher) session(conn net.Conn, reader *bufio.Reader) error {
+	defer conn.Close()
+
+	if err := p.publish(conn, reader, mqttMessage{topic: p.topic("status"), payload: []byte("online"), retain: true}); err != nil {
+		return err
+	}
+
+	ping := time.NewTicker(mqttKeepAlive / 2)
+	defer ping.Stop()
+	for {
+		for message, ok := p.next(); ok; message, ok = p.next() {
+			if err := p.publish(conn, reader, message); err != nil {
+				p.requeue(message)
+				return err
+			}
+			p.lock.Lock()
+			p.dropping = false
+			p.lock.Unlock()
+		}
+
+		select {
+		case <-p.wake:
+		case <-ping.C:
+			if err := p.exchange(conn, reader, mqttPingreq, 0, nil, mqttPingresp, nil); err != nil {
+				return err
+			}
+		case <-p.stop:
+			for message, ok := p.next(); ok; message, ok = p.next() {
+				if err := p.publish(conn, reader, message); err != nil {
+					return nil
+				}
+			}
+			// A clean disconnect discards the will, so the host is marked offline first.
+			_ = p.publish(conn, reader, mqttMessage{topic: p.topic("status"), payload: []byte("offline"), retain: true})
+			_ = writeMQTTPacket(conn, mqttDisconnect, 0, nil)
+			return nil
+		}
+	}
+}
+
+// publish sends a message with QoS 1 and waits for the broker to acknowledge it.
+func (p *MQTTPublisher) publish(conn net.Conn, reader *bufio.Reader, message mqttMessage) error {
+	p.lock.Lock()
+	p.packetID++
+	if p.packetID == 0 {
+		p.packetID = 1
+	}
+	id := binary.BigEndian.AppendUint16(nil, p.packetID)
+	p.lock.Unlock()
+
+	flags := byte(0x02)
+	if message.retain {
+		flags |= 0x01
+	}
+	body := appendMQTTString(nil, message.topic)
+	body = append(body, id...)
+	body = append(body, message.payload...)
+
+	return p.exchange(conn, reader, mqttPublish, flags, body, mqttPuback, id)
+}
+
+// exchange sends a packet and reads until the broker answers with a packet of the expected type and body.
+func (p *MQTTPublisher) exchange(conn net.Conn, reader *bufio.Reader, packetType, flags byte, body []byte,
+	answerType byte, answerBody []byte,
+) error {
+	_ = conn.SetDeadline(time.Now().Add(mqttTimeout))
+	defer func() { _ = conn.SetDeadline(time.Time{}) }()
+
+	if err := writeMQTTPacket(conn, packetType, flags, body); err != nil {
+		return err
+	}
+	for {
+		receivedType, _, received, err := readMQTTPacket(reader)
+		if err != nil {
+			return err
+		}
+		if receivedType == answerType && string(received) == string(answerBody) {
+			return nil
+		}
+	}
+}
+
+// writeMQTTPacket writes an MQTT control packet: the type and flags, the length of the body and the body.
+func writeMQTTPacket(w io.Writer, packetType, flags byte, body []byte) error {
+	packet := []byte{packetType<<4 | flags}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+
+	return err
+}
+
+// readMQTTPacket reads an MQTT control packet written by writeMQTTPacket.
+func readMQTTPacket(r *bufio.Reader) (packetType, flags byte, body []byte, err error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, 0, nil, errors.New("malformed MQTT packet length")
+		}
+		multiplier *= 128
+	}
+
+	body = make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return first >> 4, first & 0x0f, body, nil
+}
+
+// appendMQTTString appends a string prefixed with its length, as MQTT encodes strings.
+func appendMQTTString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
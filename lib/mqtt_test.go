@@ -0,0 +1,192 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBroker stands in for an MQTT broker, acknowledging every message and passing it on.
+type testBroker struct {
+	listener net.Listener
+	connects chan []string // client id, user name and password of every connection
+	messages chan mqttMessage
+
+	// dropAfter closes the first connection without acknowledging its message of that index, if not zero.
+	dropAfter int
+}
+
+func startTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	broker := &testBroker{listener: listener, connects: make(chan []string, 10), messages: make(chan mqttMessage, 100)}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for first := true; ; first = false {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(conn, first)
+		}
+	}()
+
+	return broker
+}
+
+func (b *testBroker) serve(conn net.Conn, first bool) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	packetType, _, body, err := readMQTTPacket(reader)
+	if err != nil || packetType != mqttConnect {
+		return
+	}
+	readString := func() string {
+		length := binary.BigEndian.Uint16(body)
+		value := string(body[2 : 2+length])
+		body = body[2+length:]
+		return value
+	}
+	readString() // protocol name
+	flags := body[1]
+	body = body[4:]
+	connect := []string{readString()}
+	readString() // will topic
+	readString() // will message
+	if flags&0x80 != 0 {
+		connect = append(connect, readString())
+	}
+	if flags&0x40 != 0 {
+		connect = append(connect, readString())
+	}
+	b.connects <- connect
+	_ = writeMQTTPacket(conn, mqttConnack, 0, []byte{0, 0})
+
+	for count := 1; ; count++ {
+		var flags byte
+		packetType, flags, body, err = readMQTTPacket(reader)
+		if err != nil {
+			return
+		}
+		switch packetType {
+		case mqttPingreq:
+			_ = writeMQTTPacket(conn, mqttPingresp, 0, nil)
+		case mqttPublish:
+			if first && count == b.dropAfter {
+				return
+			}
+			topic := readString()
+			id := body[:2]
+			b.messages <- mqttMessage{topic: topic, payload: body[2:], retain: flags&0x01 != 0}
+			_ = writeMQTTPacket(conn, mqttPuback, 0, id)
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+func (b *testBroker) next(t *testing.T) mqttMessage {
+	t.Helper()
+	select {
+	case message := <-b.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no message received")
+		return mqttMessage{}
+	}
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	broker := startTestBroker(t)
+	publisher, err := NewMQTTPublisher(MQTTOptions{
+		Broker:   "mqtt://" + broker.listener.Addr().String(),
+		Username: "lab",
+		Password: "secret",
+		Prefix:   "lab",
+		Hostname: "rig-1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"usb-tree-rig-1", "lab", "secret"}, <-broker.connects)
+	assert.Equal(t, mqttMessage{topic: "lab/rig-1/status", payload: []byte("online"), retain: true}, broker.next(t))
+
+	require.NoError(t, publisher.Update([]Device{probe}))
+	message := broker.next(t)
+	assert.Equal(t, "lab/rig-1/1-3.2/state", message.topic)
+	assert.True(t, message.retain)
+	var device Device
+	require.NoError(t, json.Unmarshal(message.payload, &device))
+	assert.Equal(t, "000123", device.Serial)
+
+	require.NoError(t, publisher.Update([]Device{probe}))
+	require.NoError(t, publisher.Send(Event{Type: EventRemoved, Device: probe}))
+	message = broker.next(t)
+	assert.Equal(t, "lab/rig-1/1-3.2/event", message.topic, "unchanged states are not published again")
+	assert.False(t, message.retain)
+	assert.Contains(t, string(message.payload), `"type":"removed"`)
+
+	require.NoError(t, publisher.Update([]Device{}))
+	assert.Equal(t, mqttMessage{topic: "lab/rig-1/1-3.2/state", payload: []byte{}, retain: true}, broker.next(t))
+
+	require.NoError(t, publisher.Close())
+	assert.Equal(t, mqttMessage{topic: "lab/rig-1/status", payload: []byte("offline"), retain: true}, broker.next(t))
+	assert.Error(t, publisher.Send(Event{Type: EventAdded, Device: probe}))
+}
+
+func TestMQTTPublisher_Reconnects(t *testing.T) {
+	logs = nil
+	broker := startTestBroker(t)
+	broker.dropAfter = 2
+	publisher, err := NewMQTTPublisher(MQTTOptions{Broker: "tcp://" + broker.listener.Addr().String(), Hostname: "rig-1"})
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	assert.Equal(t, []string{"usb-tree-rig-1"}, <-broker.connects)
+	assert.Equal(t, "usb-tree/rig-1/status", broker.next(t).topic)
+
+	// The broker drops the connection instead of acknowledging the first event, which is sent again after
+	// reconnecting, followed by the second one sent in between.
+	require.NoError(t, publisher.Send(Event{Type: EventAdded, Device: probe}))
+	require.Eventually(t, func() bool { return len(GetLog()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, publisher.Send(Event{Type: EventAdded, Device: bootloader}))
+
+	<-broker.connects
+	assert.Equal(t, "usb-tree/rig-1/status", broker.next(t).topic)
+	assert.Equal(t, "usb-tree/rig-1/1-3.2/event", broker.next(t).topic)
+	assert.Equal(t, "usb-tree/rig-1/1-3/event", broker.next(t).topic)
+	assert.Contains(t, GetLog()[0].Text, "MQTT broker")
+}
+
+func TestNewMQTTPublisher_Invalid(t *testing.T) {
+	for _, options := range []MQTTOptions{
+		{Broker: "http://broker"},
+		{Broker: "mqtt://"},
+		{Broker: "mqtt://broker", Password: "secret"},
+	} {
+		_, err := NewMQTTPublisher(options)
+		assert.Error(t, err, options.Broker)
+	}
+}
+
+func TestMQTTPacket(t *testing.T) {
+	var buf bytes.Buffer
+	body := make([]byte, 300)
+	require.NoError(t, writeMQTTPacket(&buf, mqttPublish, 0x03, body))
+	assert.Equal(t, []byte{0x33, 0xac, 0x02}, buf.Bytes()[:3])
+
+	packetType, flags, read, err := readMQTTPacket(bufio.NewReader(&buf))
+	require.NoError(t, err)
+	assert.Equal(t, byte(mqttPublish), packetType)
+	assert.Equal(t, byte(0x03), flags)
+	assert.Equal(t, body, read)
+}
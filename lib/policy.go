@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A ViolationKind describes why a Device breaks the usb-tree security policy.
+type ViolationKind string
+
+// These constants represent the kinds of Violation.
+const (
+	ViolationNotAllowed          ViolationKind = "not-allowed"
+	ViolationSuspiciousComposite ViolationKind = "suspicious-composite"
+)
+
+// A Violation is one reason a Device was flagged by the audit.
+type Violation struct {
+	Kind    ViolationKind `json:"kind"`
+	Message string        `json:"message"`
+}
+
+// suspiciousComposite is a combination of interface classes that is rarely legitimate in one device, such
+// as the mass storage stick that also types keystrokes used by BadUSB attacks.
+type suspiciousComposite struct {
+	classes     []string
+	description string
+}
+
+var suspiciousComposites = []suspiciousComposite{
+	{classes: []string{"storage", "hid"}, description: "storage device that also acts as a keyboard or mouse"},
+	{classes: []string{"comm", "hid"}, description: "network or serial device that also acts as a keyboard or mouse"},
+	{classes: []string{"wireless", "hid"}, description: "wireless adapter that also acts as a keyboard or mouse"},
+}
+
+// Audit returns the policy Violations of the Device. Devices outside the Allow list are only flagged when
+// the list is not empty; suspicious composite devices are always flagged. Root hubs are never flagged.
+func (c Config) Audit(device Device) []Violation {
+	if len(device.Path) == 0 {
+		return nil
+	}
+
+	var violations []Violation
+	if len(c.Allow) > 0 && !slices.ContainsFunc(c.Allow, func(m DeviceMatcher) bool { return m.Matches(device) }) {
+		violations = append(violations, Violation{
+			Kind:    ViolationNotAllowed,
+			Message: fmt.Sprintf("%s:%s is not on the allow list", device.VendorID, device.ProductID),
+		})
+	}
+
+	for _, composite := range suspiciousComposites {
+		if composite.matches(device) {
+			violations = append(violations, Violation{
+				Kind:    ViolationSuspiciousComposite,
+				Message: fmt.Sprintf("%s (%s)", composite.description, strings.Join(composite.classes, "+")),
+			})
+		}
+	}
+
+	return violations
+}
+
+// matches reports whether the interfaces of the Device cover every class of the combination.
+func (s suspiciousComposite) matches(device Device) bool {
+	for _, class := range s.classes {
+		if !slices.ContainsFunc(device.Interfaces, func(i Interface) bool { return i.Class == class }) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applyPolicy records the policy Violations of the Device on it.
+func (d *Device) applyPolicy(cfg Config) {
+	d.Violations = cfg.Audit(*d)
+}
+
+// Flagged returns the Devices with policy Violations.
+func Flagged(devices []Device) []Device {
+	var flagged []Device
+	for _, device := range devices {
+		if len(device.Violations) > 0 {
+			flagged = append(flagged, device)
+		}
+	}
+
+	return flagged
+}
+
+// addViolationLog records the Violations of a newly seen Device in the log.
+// addViolationLogs logs the violations of the Devices found connected when the polling starts.
+func addViolationLogs(devices []Device, logTime time.Time) {
+	for _, device := range devices {
+		addViolationLog(device, logTime)
+	}
+}
+
+func addViolationLog(device Device, logTime time.Time) {
+	logsLock.Lock()
+	defer logsLock.Unlock()
+
+	for _, violation := range device.Violations {
+		logs = append(logs, Log{
+			Time:      logTime,
+			Text:      fmt.Sprintf("Policy violation: %s on %s: %s", device.DisplayName(), device.PortPath(), violation.Message),
+			State:     StateViolation,
+			PortLabel: device.PortLabel,
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var duckyStick = Device{
+	Path: []int{4}, Name: "USB Flash Drive", VendorID: "0781", ProductID: "5567", Speed: "480", Bus: 1, State: StateNormal,
+	Class:      "per-interface",
+	Interfaces: []Interface{{Number: 0, Class: "storage", SubClass: 6, Protocol: 80}, {Number: 1, Class: "hid", SubClass: 1, Protocol: 1}},
+}
+
+func violationKinds(violations []Violation) []ViolationKind {
+	var result []ViolationKind
+	for _, violation := range violations {
+		result = append(result, violation.Kind)
+	}
+
+	return result
+}
+
+func TestAudit_AllowList(t *testing.T) {
+	cfg := Config{Allow: []DeviceMatcher{{VendorID: "0001"}, {VendorID: "0002", ProductID: "0020", Serial: "A1"}}}
+
+	assert.Empty(t, cfg.Audit(device1))
+	assert.Equal(t, []ViolationKind{ViolationNotAllowed}, violationKinds(cfg.Audit(device2)), "serial must match too")
+	assert.Equal(t, []ViolationKind{ViolationNotAllowed}, violationKinds(cfg.Audit(device3)))
+	assert.Empty(t, cfg.Audit(device4), "root hubs are always allowed")
+	assert.Empty(t, Config{}.Audit(device3), "an empty allow list allows everything")
+}
+
+func TestAudit_SuspiciousComposite(t *testing.T) {
+	violations := Config{}.Audit(duckyStick)
+	require.Equal(t, []ViolationKind{ViolationSuspiciousComposite}, violationKinds(violations))
+	assert.Contains(t, violations[0].Message, "storage+hid")
+
+	allowed := Config{Allow: []DeviceMatcher{{VendorID: "0781"}}}
+	assert.Equal(t, []ViolationKind{ViolationSuspiciousComposite}, violationKinds(allowed.Audit(duckyStick)),
+		"allow-listed devices are still checked for suspicious interfaces")
+
+	keyboard := Device{Path: []int{2}, VendorID: "046d", ProductID: "c31c", Interfaces: []Interface{{Class: "hid"}, {Class: "hid"}}}
+	assert.Empty(t, Config{}.Audit(keyboard))
+}
+
+func TestFlagged(t *testing.T) {
+	cfg := Config{Allow: []DeviceMatcher{{VendorID: "0001"}}}
+	devices := []Device{device4, device1, device2}
+	for i := range devices {
+		devices[i].applyPolicy(cfg)
+	}
+
+	flagged := Flagged(devices)
+	require.Len(t, flagged, 1)
+	assert.Equal(t, "0002", flagged[0].VendorID)
+}
+
+func TestDeviceDiff_LogsPolicyViolations(t *testing.T) {
+	logs = nil
+	fakeRefresh([]Device{device1})
+
+	flagged := device2
+	flagged.applyPolicy(Config{Allow: []DeviceMatcher{{VendorID: "0001"}}})
+	deviceDiff([]Device{device1, flagged}, time.Now())
+
+	var violations []Log
+	for _, log := range GetLog() {
+		if log.State == StateViolation {
+			violations = append(violations, log)
+		}
+	}
+	require.Len(t, violations, 1)
+	assert.Equal(t, "Policy violation: Device 2 on 1-2: 0002:0020 is not on the allow list", violations[0].Text)
+}
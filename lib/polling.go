@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is the time between two polls of Init unless SetPolling sets another.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// Polling sets the timing of the polls made by Init, in the polling section of the config file.
+type Polling struct {
+	// Interval is the time between two polls, DefaultPollInterval when zero.
+	Interval Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	// Settle delays the callback of Init until the Devices stopped changing for this long, so that a burst of
+	// changes, such as plugging in a hub with devices attached, results in a single update. Zero runs the
+	// callback after every poll that changed the Devices.
+	Settle Duration `yaml:"settle,omitempty" json:"settle,omitempty"`
+	// MinInterval is the least time between two callbacks of Init; later changes are coalesced into the next.
+	MinInterval Duration `yaml:"min-interval,omitempty" json:"minInterval,omitempty"`
+}
+
+// maxSettleFactor bounds how long the updates of Devices that keep changing are held back, in multiples of
+// Polling.Settle.
+const maxSettleFactor = 10
+
+var (
+	polling    Polling
+	timingLock sync.RWMutex
+)
+
+// SetPolling replaces the timing of the polls made by Init. It is read before every poll, so it also applies
+// to the running monitoring.
+func SetPolling(p Polling) {
+	timingLock.Lock()
+	polling = p
+	timingLock.Unlock()
+}
+
+// getPolling returns the timing of the polls made by Init.
+func getPolling() Polling {
+	timingLock.RLock()
+	defer timingLock.RUnlock()
+
+	return polling
+}
+
+// Validate checks that no duration of the Polling is negative.
+func (p Polling) Validate() error {
+	for name, value := range map[string]Duration{"interval": p.Interval, "settle": p.Settle, "min-interval": p.MinInterval} {
+		if value < 0 {
+			return fmt.Errorf("polling: %s is negative: %v", name, time.Duration(value))
+		}
+	}
+
+	return nil
+}
+
+// interval returns the time to wait before the next poll.
+func (p Polling) interval() time.Duration {
+	if p.Interval > 0 {
+		return time.Duration(p.Interval)
+	}
+
+	return pollInterval
+}
+
+// updateTime returns when the callback of Init runs for the changes first seen at firstChange and last seen at
+// lastChange, given the time of the previous callback.
+func (p Polling) updateTime(firstChange, lastChange, lastUpdate time.Time) time.Time {
+	settle := time.Duration(p.Settle)
+	at := lastChange.Add(settle)
+	if latest := firstChange.Add(maxSettleFactor * settle); latest.Before(at) {
+		at = latest
+	}
+	if earliest := lastUpdate.Add(time.Duration(p.MinInterval)); earliest.After(at) {
+		at = earliest
+	}
+
+	return at
+}
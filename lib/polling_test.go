@@ -0,0 +1,156 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setPolling makes polling the timing of Init for a test.
+func setPolling(t *testing.T, polling Polling) {
+	SetPolling(polling)
+	t.Cleanup(func() { SetPolling(Polling{}) })
+}
+
+func TestLoadConfig_Polling(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("polling: {interval: 1s, settle: 300ms, min-interval: 2s}\n"), 0o644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, Polling{
+		Interval:    Duration(time.Second),
+		Settle:      Duration(300 * time.Millisecond),
+		MinInterval: Duration(2 * time.Second),
+	}, cfg.Polling)
+
+	require.NoError(t, os.WriteFile(path, []byte("polling: {settle: -1s}\n"), 0o644))
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "polling: settle is negative")
+}
+
+func TestPolling_UpdateTime(t *testing.T) {
+	start := time.Now()
+	at := func(offset time.Duration) time.Time { return start.Add(offset) }
+
+	assert.Equal(t, at(time.Second), Polling{}.updateTime(at(time.Second), at(time.Second), start))
+	settle := Polling{Settle: Duration(100 * time.Millisecond)}
+	assert.Equal(t, at(350*time.Millisecond), settle.updateTime(at(200*time.Millisecond), at(250*time.Millisecond), start))
+	assert.Equal(t, at(time.Second), settle.updateTime(at(0), at(5*time.Second), start), "held back at most 10 settle periods")
+	minInterval := Polling{MinInterval: Duration(time.Second)}
+	assert.Equal(t, at(time.Second), minInterval.updateTime(at(10*time.Millisecond), at(10*time.Millisecond), start))
+	assert.Equal(t, at(2*time.Second), minInterval.updateTime(at(2*time.Second), at(2*time.Second), start))
+}
+
+// growingPolls makes the polls of Init return one more of devices each time, then all of them.
+func growingPolls(t *testing.T, devices ...Device) {
+	var lock sync.Mutex
+	polls := 0
+	pollDevices = func() (time.Time, []Device, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		polls++
+		return time.Now(), devices[:min(polls, len(devices))], nil
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+}
+
+func TestInit_SettleCoalescesBursts(t *testing.T) {
+	fastPolling(t)
+	setPolling(t, Polling{Settle: Duration(50 * time.Millisecond)})
+	growingPolls(t, device4, device1, device2, device3)
+
+	updates := make(chan []Device, 100)
+	_, err := Init(func(devices []Device) { updates <- devices })
+	require.NoError(t, err)
+	t.Cleanup(func() { stopPolling(t) })
+
+	assert.Len(t, nextUpdate(t, updates), 1)
+	assert.Len(t, nextUpdate(t, updates), 4)
+	select {
+	case devices := <-updates:
+		assert.Fail(t, "unexpected update", "devices: %v", deviceNames(devices))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInit_MinInterval(t *testing.T) {
+	fastPolling(t)
+	setPolling(t, Polling{MinInterval: Duration(30 * time.Millisecond)})
+	var lock sync.Mutex
+	polls := 0
+	pollDevices = func() (time.Time, []Device, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		polls++
+		if polls%2 == 0 {
+			return time.Now(), []Device{device4, device1}, nil
+		}
+		return time.Now(), []Device{device4}, nil
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+
+	updates := make(chan time.Time, 100)
+	_, err := Init(func([]Device) { updates <- time.Now() })
+	require.NoError(t, err)
+	t.Cleanup(func() { stopPolling(t) })
+
+	last := <-updates
+	for range 3 {
+		select {
+		case next := <-updates:
+			assert.GreaterOrEqual(t, next.Sub(last), 30*time.Millisecond)
+			last = next
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "no update received")
+		}
+	}
+}
+
+func TestRefresh_WhilePolling(t *testing.T) {
+	setPolling(t, Polling{Interval: Duration(time.Hour)})
+	logs = nil
+	var lock sync.Mutex
+	connected := []Device{device1}
+	pollDevices = func() (time.Time, []Device, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return time.Now(), slices.Clone(connected), nil
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+	events, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	updates := make(chan []Device, 10)
+	_, err := Init(func(devices []Device) { updates <- devices })
+	require.NoError(t, err)
+	defer stopPolling(t)
+	nextUpdate(t, updates)
+
+	lock.Lock()
+	connected = append(connected, device2)
+	lock.Unlock()
+	_, devices, err := Refresh()
+	require.NoError(t, err)
+	assert.Len(t, devices, 2)
+	assert.False(t, hasState(devices, StateAdded), "a refresh should reset the states")
+
+	select {
+	case event := <-events:
+		assert.Equal(t, EventAdded, event.Type, "changes since the last poll should be published")
+		assert.Equal(t, device2.Key(), event.Device.Key())
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no event published")
+	}
+	assert.Len(t, GetLog(), 1)
+	assert.Len(t, nextUpdate(t, updates), 2)
+
+	RequestRefresh()
+	assert.Len(t, nextUpdate(t, updates), 2, "a requested refresh should run the callback")
+}
@@ -0,0 +1,26 @@
+package lib
+
+// A PortLabel names a physical location, such as a hub port or a chassis connector, by its port path.
+// The label applies to whatever Device is plugged into that port.
+type PortLabel struct {
+	Port  string `yaml:"port" json:"port"`
+	Label string `yaml:"label" json:"label"`
+}
+
+// FindPortLabel returns the label configured for the given port path.
+func (c Config) FindPortLabel(port string) (string, bool) {
+	for _, portLabel := range c.PortLabels {
+		if portLabel.Port == port {
+			return portLabel.Label, true
+		}
+	}
+
+	return "", false
+}
+
+// applyPortLabel copies the label of the port the Device is plugged into onto it.
+func (d *Device) applyPortLabel(cfg Config) {
+	if label, ok := cfg.FindPortLabel(d.PortPath()); ok {
+		d.PortLabel = label
+	}
+}
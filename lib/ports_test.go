@@ -0,0 +1,45 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var portLabels = Config{PortLabels: []PortLabel{
+	{Port: "1-3.2", Label: "hub A port 2"},
+	{Port: "usb1", Label: "rack 2 front-left"},
+}}
+
+func TestApplyPortLabel(t *testing.T) {
+	d := probe
+	d.applyPortLabel(portLabels)
+	assert.Equal(t, "hub A port 2", d.PortLabel)
+
+	root := device4
+	root.applyPortLabel(portLabels)
+	assert.Equal(t, "rack 2 front-left", root.PortLabel)
+
+	unlabeled := device1
+	unlabeled.applyPortLabel(portLabels)
+	assert.Empty(t, unlabeled.PortLabel)
+}
+
+func TestPortLabelFollowsPort(t *testing.T) {
+	other := Device{Path: []int{3, 2}, Name: "Other", VendorID: "abcd", ProductID: "0001", Bus: 1}
+	other.applyPortLabel(portLabels)
+	assert.Equal(t, "hub A port 2", other.PortLabel, "the label belongs to the port, not the device")
+}
+
+func TestRemovedLogHasPortLabel(t *testing.T) {
+	labeled := probe
+	labeled.applyPortLabel(portLabels)
+	fakeRefresh([]Device{device1, labeled})
+	logs = nil
+
+	deviceDiff([]Device{device1}, time.Now())
+	assert.Len(t, logs, 1)
+	assert.Equal(t, StateRemoved, logs[0].State)
+	assert.Equal(t, "hub A port 2", logs[0].PortLabel)
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package lib
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sysfsUSBDevices is the directory of the USB devices in sysfs; tests replace it.
+var sysfsUSBDevices = "/sys/bus/usb/devices"
+
+// sysfsPowerEnricher adds the runtime power management state of the Devices from sysfs.
+type sysfsPowerEnricher struct{}
+
+func (sysfsPowerEnricher) Name() string {
+	return "sysfs-power"
+}
+
+func (sysfsPowerEnricher) Enrich(d *Device) error {
+	dir := filepath.Join(sysfsUSBDevices, d.PortPath(), "power")
+
+	status, err := readSysfsAttr(filepath.Join(dir, "runtime_status"))
+	if err != nil {
+		return err
+	}
+	if d.PowerState == "" {
+		d.PowerState = status
+	}
+	control, err := readSysfsAttr(filepath.Join(dir, "control"))
+	if err != nil {
+		return err
+	}
+	if control == "auto" {
+		d.Autosuspend = true
+	}
+
+	return nil
+}
+
+// readSysfsAttr returns the value of a sysfs attribute, or "" when the kernel does not provide it.
+func readSysfsAttr(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSysfsPowerEnricher(t *testing.T) {
+	dir := t.TempDir()
+	sysfsUSBDevices = dir
+	t.Cleanup(func() { sysfsUSBDevices = "/sys/bus/usb/devices" })
+	power := filepath.Join(dir, "1-3.2", "power")
+	require.NoError(t, os.MkdirAll(power, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(power, "runtime_status"), []byte("suspended\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(power, "control"), []byte("auto\n"), 0o644))
+
+	device := probe
+	require.NoError(t, sysfsPowerEnricher{}.Enrich(&device))
+	assert.Equal(t, "suspended", device.PowerState)
+	assert.True(t, device.Autosuspend)
+
+	unplugged := device1
+	require.NoError(t, sysfsPowerEnricher{}.Enrich(&unplugged), "missing attributes are no error")
+	assert.Empty(t, unplugged.PowerState)
+}
@@ -0,0 +1,381 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Rule runs its Actions whenever an Event of one of the types in On happens to a Device selected by Match
+// and Filter. An empty On matches every event type.
+type Rule struct {
+	Name    string        `yaml:"name" json:"name"`
+	On      []EventType   `yaml:"on,omitempty" json:"on,omitempty"`
+	Match   DeviceMatcher `yaml:"match,omitempty" json:"match"`
+	Filter  Filter        `yaml:"filter,omitempty" json:"filter,omitzero"`
+	Actions []Action      `yaml:"actions" json:"actions"`
+}
+
+// An Action is one step of a Rule. Exactly one of Run, Webhook or Log is set. Arguments of Run, the Log
+// message and the File path are text/template strings evaluated against the RuleContext of the Event.
+//
+// Run executes a command directly, without a shell, with the USB_* variables of the Event in its environment.
+// Webhook POSTs the Event as JSON to a URL. Log writes a message to the usb-tree log, or appends it to File.
+type Action struct {
+	Run     Command  `yaml:"run,omitempty" json:"run,omitempty"`
+	Webhook string   `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+	Log     string   `yaml:"log,omitempty" json:"log,omitempty"`
+	File    string   `yaml:"file,omitempty" json:"file,omitempty"`
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// A Command is a program and its arguments. In YAML it is either a list or a single string split on the
+// spaces outside {{ }} actions; the list form allows arguments with spaces.
+type Command []string
+
+// UnmarshalYAML accepts both the list and the string form of a Command.
+func (c *Command) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = splitCommand(node.Value)
+		return nil
+	}
+
+	var args []string
+	if err := node.Decode(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// splitCommand splits the string form of a Command on the spaces outside {{ }} actions, so that
+// "flash.sh {{ .DevNode }}" is two arguments.
+func splitCommand(text string) Command {
+	var args Command
+	var arg strings.Builder
+	inAction := false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case !inAction && strings.HasPrefix(text[i:], "{{"), inAction && strings.HasPrefix(text[i:], "}}"):
+			inAction = !inAction
+			arg.WriteString(text[i : i+2])
+			i++
+		case !inAction && (text[i] == ' ' || text[i] == '\t' || text[i] == '\n'):
+			if arg.Len() > 0 {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+		default:
+			arg.WriteByte(text[i])
+		}
+	}
+	if arg.Len() > 0 {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// A Duration is a time.Duration written as a string such as "30s" in the config file.
+type Duration time.Duration
+
+// UnmarshalYAML parses a Duration string.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML writes a Duration as a string.
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+// RuleContext is the data available to Action templates, e.g. {{.DevNode}} or {{.Port}}.
+type RuleContext struct {
+	Device
+	Rule  string
+	Event EventType
+	Time  time.Time
+	Port  string
+}
+
+// defaultActionTimeout bounds how long a command or webhook may run.
+const defaultActionTimeout = 30 * time.Second
+
+// actionsRunning tracks the actions started by runRules so tests can wait for them.
+var actionsRunning sync.WaitGroup
+
+// Validate checks that the Rule has a matcher and well-formed actions.
+func (r Rule) Validate() error {
+	if r.Match.IsZero() && r.Filter.IsZero() {
+		return fmt.Errorf("rule %q: match and filter are empty", r.Name)
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("rule %q: no actions", r.Name)
+	}
+	for _, eventType := range r.On {
+		if !slices.Contains(EventTypes(), eventType) {
+			return fmt.Errorf("rule %q: unknown event %q", r.Name, eventType)
+		}
+	}
+
+	for i, action := range r.Actions {
+		if err := action.validate(); err != nil {
+			return fmt.Errorf("rule %q: action %d: %w", r.Name, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (a Action) validate() error {
+	kinds := 0
+	for _, set := range []bool{len(a.Run) > 0, a.Webhook != "", a.Log != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("exactly one of run, webhook or log must be set")
+	}
+	if a.File != "" && a.Log == "" {
+		return errors.New("file is only valid with log")
+	}
+
+	if a.Webhook != "" {
+		if u, err := url.Parse(a.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid webhook URL %q", a.Webhook)
+		}
+	}
+
+	for _, text := range append(slices.Clone(a.Run), a.Log, a.File) {
+		if _, err := template.New("").Parse(text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether the Rule applies to the Event.
+func (r Rule) Matches(event Event) bool {
+	if len(r.On) > 0 && !slices.Contains(r.On, event.Type) {
+		return false
+	}
+
+	if r.Match.IsZero() && r.Filter.IsZero() {
+		return false
+	}
+
+	return (r.Match.IsZero() || r.Match.Matches(event.Device)) && r.Filter.Matches(event.Device)
+}
+
+// runRules queues the actions of every Rule matching the Event. The actions of the Rules run one after the
+// other, in order, and after those of the earlier Events of the same Device.
+func runRules(rules []Rule, event Event) {
+	for _, rule := range rules {
+		if !rule.Matches(event) {
+			continue
+		}
+
+		ruleContext := RuleContext{
+			Device: event.Device,
+			Rule:   rule.Name,
+			Event:  event.Type,
+			Time:   event.Time,
+			Port:   event.Device.PortPath(),
+		}
+		queueActions(event.Device.Key(), func() {
+			for _, action := range rule.Actions {
+				if err := action.run(ruleContext, event); err != nil {
+					getLogger().Error("rule action failed", "rule", rule.Name, "device", event.Device.Key(), "error", err)
+					addErrorLog(fmt.Sprintf("Rule %q failed: %s", rule.Name, err.Error()), time.Now(), StateError)
+				}
+			}
+		})
+	}
+}
+
+// actionQueues holds the actions waiting to run for each Device key. A Device has an entry while a goroutine
+// runs its actions; the actions of different Devices run concurrently.
+var (
+	actionQueues     = map[string][]func(){}
+	actionQueuesLock sync.Mutex
+)
+
+// queueActions runs the actions after those queued before for the same Device key.
+func queueActions(key string, actions func()) {
+	actionsRunning.Add(1)
+
+	actionQueuesLock.Lock()
+	defer actionQueuesLock.Unlock()
+	queue, running := actionQueues[key]
+	actionQueues[key] = append(queue, actions)
+	if !running {
+		go runActionQueue(key)
+	}
+}
+
+// runActionQueue runs the queued actions of the Device key until there are none left.
+func runActionQueue(key string) {
+	for {
+		actionQueuesLock.Lock()
+		queue := actionQueues[key]
+		if len(queue) == 0 {
+			delete(actionQueues, key)
+			actionQueuesLock.Unlock()
+			return
+		}
+		actions := queue[0]
+		actionQueues[key] = queue[1:]
+		actionQueuesLock.Unlock()
+
+		actions()
+		actionsRunning.Done()
+	}
+}
+
+func (a Action) run(ruleContext RuleContext, event Event) error {
+	timeout := time.Duration(a.Timeout)
+	if timeout <= 0 {
+		timeout = defaultActionTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch {
+	case len(a.Run) > 0:
+		return a.runCommand(ctx, ruleContext)
+	case a.Webhook != "":
+		return a.postWebhook(ctx, ruleContext, event)
+	default:
+		return a.writeLog(ruleContext)
+	}
+}
+
+func (a Action) runCommand(ctx context.Context, ruleContext RuleContext) error {
+	args := make([]string, len(a.Run))
+	for i, arg := range a.Run {
+		expanded, err := expand(arg, ruleContext)
+		if err != nil {
+			return err
+		}
+		args[i] = expanded
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), ruleContext.environment()...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func (a Action) postWebhook(ctx context.Context, ruleContext RuleContext, event Event) error {
+	body, err := json.Marshal(struct {
+		Rule string `json:"rule"`
+		Event
+	}{Rule: ruleContext.Rule, Event: event})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", a.Webhook, resp.Status)
+	}
+
+	return nil
+}
+
+func (a Action) writeLog(ruleContext RuleContext) error {
+	message, err := expand(a.Log, ruleContext)
+	if err != nil {
+		return err
+	}
+
+	if a.File == "" {
+		addErrorLog(message, ruleContext.Time, StateNormal)
+		return nil
+	}
+
+	path, err := expand(a.File, ruleContext)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", ruleContext.Time.Format(time.RFC3339), message)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// expand evaluates a template string against the RuleContext.
+func expand(text string, ruleContext RuleContext) (string, error) {
+	tmpl, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, ruleContext); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// environment returns the USB_* variables describing the Event for commands.
+func (c RuleContext) environment() []string {
+	return []string{
+		"USB_EVENT=" + string(c.Event),
+		"USB_RULE=" + c.Rule,
+		"USB_NAME=" + c.Name,
+		"USB_ALIAS=" + c.Alias,
+		"USB_VID=" + c.VendorID,
+		"USB_PID=" + c.ProductID,
+		"USB_SERIAL=" + c.Serial,
+		"USB_CLASS=" + c.Class,
+		"USB_BUS=" + strconv.Itoa(c.Bus),
+		"USB_DEVNUM=" + strconv.Itoa(c.DevNum),
+		"USB_PORT=" + c.Port,
+		"USB_PORT_LABEL=" + c.PortLabel,
+		"USB_DEVNODE=" + c.DevNode,
+		"USB_SPEED=" + c.Speed,
+	}
+}
@@ -0,0 +1,223 @@
+package lib
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+var bootloader = Device{
+	Path: []int{3}, Name: "STM32 BOOTLOADER", VendorID: "0483", ProductID: "df11", Speed: "12", Bus: 1,
+	Serial: "385B37673033", DevNode: "/dev/bus/usb/001/007", State: StateAdded,
+}
+
+// runEvents publishes events with the given rules and waits for their actions to finish.
+func runEvents(t *testing.T, rules []Rule, events ...Event) {
+	t.Helper()
+	for _, event := range events {
+		runRules(rules, event)
+	}
+	actionsRunning.Wait()
+}
+
+func TestRule_Matches(t *testing.T) {
+	rule := Rule{On: []EventType{EventAdded}, Match: DeviceMatcher{VendorID: "0483", ProductID: "df11"}}
+
+	assert.True(t, rule.Matches(Event{Type: EventAdded, Device: bootloader}))
+	assert.False(t, rule.Matches(Event{Type: EventRemoved, Device: bootloader}))
+	assert.False(t, rule.Matches(Event{Type: EventAdded, Device: probe}))
+
+	anyEvent := Rule{Match: DeviceMatcher{Port: "1-*"}}
+	assert.True(t, anyEvent.Matches(Event{Type: EventRemoved, Device: bootloader}))
+	assert.True(t, anyEvent.Matches(Event{Type: EventChanged, Device: bootloader}))
+}
+
+func TestRule_MatchesFilter(t *testing.T) {
+	filter, err := ParseFilter(`name~bootloader speed<=12`)
+	require.NoError(t, err)
+	rule := Rule{Filter: filter}
+	assert.True(t, rule.Matches(Event{Type: EventAdded, Device: bootloader}))
+	assert.False(t, rule.Matches(Event{Type: EventAdded, Device: probe}))
+
+	rule.Match = DeviceMatcher{Serial: "000123"}
+	assert.False(t, rule.Matches(Event{Type: EventAdded, Device: bootloader}), "both the match and the filter apply")
+
+	assert.False(t, Rule{}.Matches(Event{Type: EventAdded, Device: bootloader}))
+}
+
+func TestRule_Validate(t *testing.T) {
+	match := DeviceMatcher{VendorID: "0483"}
+
+	assert.NoError(t, Rule{Name: "ok", Match: match, Actions: []Action{{Log: "{{.Name}} added"}}}.Validate())
+	assert.Error(t, Rule{Name: "no match", Actions: []Action{{Log: "x"}}}.Validate())
+	filter, err := ParseFilter("vid:0483")
+	require.NoError(t, err)
+	assert.NoError(t, Rule{Name: "filter", Filter: filter, Actions: []Action{{Log: "x"}}}.Validate())
+	assert.Error(t, Rule{Name: "no actions", Match: match}.Validate())
+	assert.Error(t, Rule{Name: "bad event", On: []EventType{"plugged"}, Match: match, Actions: []Action{{Log: "x"}}}.Validate())
+	assert.Error(t, Rule{Name: "two kinds", Match: match, Actions: []Action{{Log: "x", Webhook: "http://localhost"}}}.Validate())
+	assert.Error(t, Rule{Name: "bad url", Match: match, Actions: []Action{{Webhook: "localhost:9000"}}}.Validate())
+	assert.Error(t, Rule{Name: "bad template", Match: match, Actions: []Action{{Run: Command{"flash.sh", "{{.DevNode"}}}}.Validate())
+	assert.Error(t, Rule{Name: "file without log", Match: match, Actions: []Action{{Run: Command{"true"}, File: "x"}}}.Validate())
+}
+
+func TestRules_YAML(t *testing.T) {
+	data := `
+rules:
+  - name: flash bootloader
+    on: [added]
+    match: {vid: "0483", pid: df11}
+    actions:
+      - run: flash.sh {{.DevNode}}
+        timeout: 2m
+  - name: hid removed
+    on: [removed]
+    match: {class: hid, port: "1-3*"}
+    actions:
+      - webhook: http://localhost:9000/hook
+      - run: ["notify-send", "{{.Name}} removed"]
+  - name: slow storage
+    filter: class:storage speed<480 name~"flash drive"
+    actions:
+      - log: "{{.Name}} is slow"
+`
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(data), &cfg))
+	require.NoError(t, cfg.Validate())
+	require.Len(t, cfg.Rules, 3)
+
+	assert.Equal(t, Command{"flash.sh", "{{.DevNode}}"}, cfg.Rules[0].Actions[0].Run)
+	assert.Equal(t, Duration(2*time.Minute), cfg.Rules[0].Actions[0].Timeout)
+	assert.Equal(t, []EventType{EventRemoved}, cfg.Rules[1].On)
+	assert.Equal(t, Command{"notify-send", "{{.Name}} removed"}, cfg.Rules[1].Actions[1].Run)
+	assert.Equal(t, `class:storage speed<480 name~"flash drive"`, cfg.Rules[2].Filter.String())
+
+	out, err := yaml.Marshal(cfg.Rules[2])
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `filter: class:storage speed<480 name~"flash drive"`)
+	assert.NotContains(t, string(out), "match:")
+
+	var invalid Config
+	err = yaml.Unmarshal([]byte("rules:\n  - name: typo\n    filter: vendorid:0483\n"), &invalid)
+	assert.ErrorIs(t, err, ErrUnknownFilterKey)
+}
+
+func TestCommand_UnmarshalYAML(t *testing.T) {
+	tests := map[string]Command{
+		`flash.sh {{.DevNode}}`:                          {"flash.sh", "{{.DevNode}}"},
+		`flash.sh {{ .DevNode }}`:                        {"flash.sh", "{{ .DevNode }}"},
+		`notify {{ printf "%s on %s" .Name .Port }} now`: {"notify", `{{ printf "%s on %s" .Name .Port }}`, "now"},
+		`dfu-util  -d {{ .VendorID }}:{{ .ProductID }}`:  {"dfu-util", "-d", "{{ .VendorID }}:{{ .ProductID }}"},
+	}
+	for value, want := range tests {
+		var command Command
+		require.NoError(t, yaml.Unmarshal([]byte(`'`+value+`'`), &command))
+		assert.Equal(t, want, command, value)
+	}
+
+	var action Action
+	require.NoError(t, yaml.Unmarshal([]byte("run: flash.sh {{ .DevNode }}"), &action))
+	assert.Equal(t, Command{"flash.sh", "{{ .DevNode }}"}, action.Run)
+	require.NoError(t, Rule{Name: "flash", Match: DeviceMatcher{VendorID: "0483"}, Actions: []Action{action}}.Validate())
+}
+
+func TestRuleAction_Log(t *testing.T) {
+	logs = nil
+	rules := []Rule{{Name: "log", Match: DeviceMatcher{VendorID: "0483"}, Actions: []Action{{Log: "{{.Event}} {{.VendorID}}:{{.ProductID}} on {{.Port}}"}}}}
+
+	runEvents(t, rules, Event{Time: time.Now(), Type: EventAdded, Device: bootloader})
+	got := GetLog()
+	require.Len(t, got, 1)
+	assert.Equal(t, "added 0483:df11 on 1-3", got[0].Text)
+}
+
+func TestRuleAction_LogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	rules := []Rule{{Name: "file", Match: DeviceMatcher{Serial: bootloader.Serial}, Actions: []Action{{Log: "{{.Serial}} {{.Event}}", File: path}}}}
+
+	runEvents(t, rules,
+		Event{Time: time.Now(), Type: EventAdded, Device: bootloader},
+		Event{Time: time.Now(), Type: EventRemoved, Device: bootloader},
+	)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "385B37673033 added"))
+	assert.True(t, strings.HasSuffix(lines[1], "385B37673033 removed"))
+}
+
+func TestRuleAction_Run(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	rules := []Rule{{
+		Name:    "flash",
+		On:      []EventType{EventAdded},
+		Match:   DeviceMatcher{VendorID: "0483", ProductID: "df11"},
+		Actions: []Action{{Run: Command{"sh", "-c", `echo "$USB_VID:$USB_PID $USB_PORT $1" > ` + out, "sh", "{{.DevNode}}"}}},
+	}}
+
+	runEvents(t, rules, Event{Time: time.Now(), Type: EventAdded, Device: bootloader})
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "0483:df11 1-3 /dev/bus/usb/001/007\n", string(data))
+}
+
+func TestRuleAction_RunFailureIsLogged(t *testing.T) {
+	logs = nil
+	rules := []Rule{{Name: "fail", Match: DeviceMatcher{VendorID: "0483"}, Actions: []Action{{Run: Command{"false"}}}}}
+
+	runEvents(t, rules, Event{Time: time.Now(), Type: EventAdded, Device: bootloader})
+	got := GetLog()
+	require.Len(t, got, 1)
+	assert.Equal(t, StateError, got[0].State)
+	assert.Contains(t, got[0].Text, `Rule "fail" failed`)
+}
+
+func TestRuleAction_Webhook(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]any
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		received <- payload
+	}))
+	defer server.Close()
+
+	removed := bootloader
+	removed.State = StateRemoved
+	rules := []Rule{{Name: "hook", On: []EventType{EventRemoved}, Match: DeviceMatcher{Port: "1-3"}, Actions: []Action{{Webhook: server.URL}}}}
+
+	runEvents(t, rules, Event{Time: time.Now(), Type: EventRemoved, Device: removed})
+	payload := <-received
+	assert.Equal(t, "hook", payload["rule"])
+	assert.Equal(t, "removed", payload["type"])
+	assert.Equal(t, "df11", payload["device"].(map[string]any)["productId"])
+}
+
+func TestDeviceDiffRunsRules(t *testing.T) {
+	defer SetConfig(Config{})
+	logs = nil
+	fakeRefresh([]Device{device1})
+	SetConfig(Config{Rules: []Rule{{Name: "log", On: []EventType{EventAdded}, Match: DeviceMatcher{VendorID: "0483"}, Actions: []Action{{Log: "bootloader ready"}}}}})
+
+	deviceDiff([]Device{device1, bootloader}, time.Now())
+	actionsRunning.Wait()
+
+	texts := []string{}
+	for _, log := range GetLog() {
+		texts = append(texts, log.Text)
+	}
+	assert.Contains(t, texts, "bootloader ready")
+}
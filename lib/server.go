@@ -0,0 +1,330 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	streamBuffer    = 256              // messages a stream client may fall behind before it is disconnected
+	streamKeepAlive = 15 * time.Second // how often an idle stream is sent a comment, so clients see it is alive
+)
+
+// A Server shares the Devices, tree, log and Events seen by the monitor over HTTP, so tools can use the same
+// data as the UIs without linking Go:
+//
+//	GET /devices         the Devices, as passed to the Init callback
+//	GET /devices/{key}   one Device by port path, e.g. "1-3.2", or by Key
+//	GET /tree            the Devices as a tree of TreeNodes
+//	GET /logs?since=     the log, optionally only entries after an RFC 3339 time or, with after=, a Seq
+//	GET /events          a Server-Sent Events stream of "host", "devices", "log" and Event messages
+//	POST /refresh        polls the Devices now, like Refresh
+//	GET /metrics         metrics for Prometheus, see WriteMetrics
+//	GET /health          the Health of the monitor, with a 503 status while it fails
+type Server struct {
+	mux *http.ServeMux
+
+	lock      sync.RWMutex
+	devices   []Device
+	logCount  int
+	clients   map[chan streamMessage]struct{}
+	listeners []func([]Device)
+}
+
+// streamMessage is one Server-Sent Event.
+type streamMessage struct {
+	event string
+	data  []byte
+}
+
+// NewServer returns a Server with no Devices. Run fills it from the monitor.
+func NewServer() *Server {
+	s := &Server{mux: http.NewServeMux(), clients: make(map[chan streamMessage]struct{})}
+	s.mux.HandleFunc("GET /devices", s.handleDevices)
+	s.mux.HandleFunc("GET /devices/{key}", s.handleDevice)
+	s.mux.HandleFunc("GET /tree", s.handleTree)
+	s.mux.HandleFunc("GET /logs", s.handleLogs)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	s.mux.HandleFunc("POST /refresh", s.handleRefresh)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+
+	return s
+}
+
+// Run starts the monitor and feeds its updates and Events to the Server until ctx is done.
+func (s *Server) Run(ctx context.Context) {
+	events, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	// A failed first poll is reported by Health and the log, while the monitor keeps retrying.
+	_, _ = Init(s.update)
+	defer Stop()
+
+	for {
+		select {
+		case event := <-events:
+			s.broadcast(string(event.Type), event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// OnUpdate registers a function called with the Devices of every update of the monitor, or nil for a failed
+// poll, after the Server stored them.
+func (s *Server) OnUpdate(listener func([]Device)) {
+	s.lock.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.lock.Unlock()
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// update stores the Devices of a poll and streams them with the log entries added since the last one. A nil
+// slice, for a failed poll, keeps the previous Devices.
+func (s *Server) update(devices []Device) {
+	logs := GetLog()
+
+	s.lock.Lock()
+	if s.logCount > len(logs) {
+		s.logCount = 0
+	}
+	first := s.logCount
+	newLogs := logs[first:]
+	s.logCount = len(logs)
+	if devices != nil {
+		s.devices = devices
+	}
+	listeners := slices.Clone(s.listeners)
+	s.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener(devices)
+	}
+
+	for i, log := range newLogs {
+		log.Seq = first + i + 1
+		s.broadcast("log", log)
+	}
+	if devices != nil {
+		s.broadcast("devices", devices)
+	}
+}
+
+// getDevices returns the Devices of the last update.
+func (s *Server) getDevices() []Device {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.devices
+}
+
+// broadcast sends a message to every stream client. Clients that are not keeping up are disconnected so
+// they can reconnect and start over from the current Devices.
+func (s *Server) broadcast(event string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		addErrorLog(fmt.Sprintf("Failed to encode %s message: %v", event, err), time.Now(), StateError)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for client := range s.clients {
+		select {
+		case client <- streamMessage{event: event, data: data}:
+		default:
+			delete(s.clients, client)
+			close(client)
+		}
+	}
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, _ *http.Request) {
+	respondJSON(w, nonNilDevices(s.getDevices()))
+}
+
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	for _, device := range s.getDevices() {
+		if device.PortPath() == key || device.Key() == key {
+			respondJSON(w, device)
+			return
+		}
+	}
+
+	http.Error(w, fmt.Sprintf("no device %q", key), http.StatusNotFound)
+}
+
+func (s *Server) handleTree(w http.ResponseWriter, _ *http.Request) {
+	tree := BuildDeviceTree(s.getDevices())
+	if tree == nil {
+		tree = []*TreeNode{}
+	}
+
+	respondJSON(w, tree)
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if value := r.URL.Query().Get("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339Nano, value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid since time %q, expected RFC 3339", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	logs := GetLog()
+	var after int
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		if after, err = strconv.Atoi(value); err != nil || after < 0 {
+			http.Error(w, fmt.Sprintf("invalid after %q, expected a sequence number", value), http.StatusBadRequest)
+			return
+		}
+		if after > len(logs) {
+			after = 0 // the daemon restarted since, so its whole log is new
+		}
+	}
+
+	entries := []Log{}
+	for i, log := range logs[after:] {
+		if log.Time.After(since) {
+			log.Seq = after + i + 1
+			entries = append(entries, log)
+		}
+	}
+
+	respondJSON(w, entries)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_ = WriteMetrics(w, s.getDevices())
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, _ *http.Request) {
+	if _, _, err := Refresh(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleHealth responds with the Health of the monitor, with a 503 status unless it works or is starting, for
+// health checks.
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	status := Health()
+	w.Header().Set("Content-Type", "application/json")
+	if status.State == HealthFailing || status.State == HealthStopped {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// handleEvents streams the host name and the current Devices, followed by every update, log entry and Event.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	client := make(chan streamMessage, streamBuffer)
+	s.lock.Lock()
+	s.clients[client] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		if _, ok := s.clients[client]; ok {
+			delete(s.clients, client)
+			close(client)
+		}
+		s.lock.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	hostname, _ := os.Hostname()
+	host, _ := json.Marshal(struct {
+		Hostname string `json:"hostname"`
+	}{hostname})
+	writeStreamMessage(w, streamMessage{event: "host", data: host})
+	devices, _ := json.Marshal(nonNilDevices(s.getDevices()))
+	writeStreamMessage(w, streamMessage{event: "devices", data: devices})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-keepAlive.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case message, ok := <-client:
+			if !ok {
+				return
+			}
+			writeStreamMessage(w, message)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamMessage(w http.ResponseWriter, message streamMessage) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", message.event, message.data)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func respondJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// nonNilDevices turns a nil slice into an empty one so it is encoded as [] rather than null.
+func nonNilDevices(devices []Device) []Device {
+	if devices == nil {
+		return []Device{}
+	}
+
+	return devices
+}
+
+// Listen opens the listener for a Server address: "unix:" followed by the path of a unix socket, or a TCP
+// host and port such as "127.0.0.1:8787". A stale socket file left by a previous run is removed.
+func Listen(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, "unix:")
+	if !ok {
+		return net.Listen("tcp", address)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			_ = os.Remove(path)
+		} else {
+			_ = conn.Close()
+		}
+	}
+
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,169 @@
+package lib
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getJSON(t *testing.T, url string, value any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(value))
+	}
+
+	return resp.StatusCode
+}
+
+func TestServer_Devices(t *testing.T) {
+	server := NewServer()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	var devices []Device
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/devices", &devices))
+	assert.Empty(t, devices)
+
+	var updated []Device
+	server.OnUpdate(func(devices []Device) { updated = devices })
+	server.update([]Device{device4, probe})
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/devices", &devices))
+	assert.Len(t, devices, 2)
+	assert.Len(t, updated, 2)
+
+	var device Device
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/devices/1-3.2", &device))
+	assert.Equal(t, "SEGGER J-Link", device.Name)
+	assert.Equal(t, http.StatusNotFound, getJSON(t, httpServer.URL+"/devices/1-9", &device))
+
+	var tree []TreeNode
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/tree", &tree))
+	require.Len(t, tree, 1)
+	assert.Equal(t, "Root", tree[0].Name)
+}
+
+func TestServer_Logs(t *testing.T) {
+	logs = nil
+	start := time.Now()
+	addErrorLog("first", start, StateError)
+	addErrorLog("second", start.Add(time.Second), StateError)
+
+	httpServer := httptest.NewServer(NewServer())
+	defer httpServer.Close()
+
+	var entries []Log
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/logs", &entries))
+	assert.Len(t, entries, 2)
+
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/logs?since="+start.Format(time.RFC3339Nano), &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Text)
+
+	assert.Equal(t, 2, entries[0].Seq)
+
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/logs?after=1", &entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "second", entries[0].Text)
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/logs?after=5", &entries))
+	assert.Len(t, entries, 2, "an after beyond the log should return all of it")
+
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, httpServer.URL+"/logs?since=yesterday", &entries))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, httpServer.URL+"/logs?after=-1", &entries))
+}
+
+func TestServer_Events(t *testing.T) {
+	logs = nil
+	server := NewServer()
+	server.update([]Device{device4})
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/events", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Reads the next message as its event name and data.
+	reader := bufio.NewReader(resp.Body)
+	next := func() (string, string) {
+		var event, data string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return event, data
+			}
+			if value, ok := strings.CutPrefix(line, "event: "); ok {
+				event = value
+			}
+			if value, ok := strings.CutPrefix(line, "data: "); ok {
+				data = value
+			}
+		}
+	}
+
+	event, data := next()
+	assert.Equal(t, "host", event)
+	assert.Contains(t, data, `"hostname":`)
+	event, data = next()
+	assert.Equal(t, "devices", event)
+	assert.Contains(t, data, `"name":"Root"`)
+
+	addErrorLog("poll failed", time.Now(), StateError)
+	server.update([]Device{device4, probe})
+	server.broadcast(string(EventAdded), Event{Type: EventAdded, Device: probe})
+
+	event, data = next()
+	assert.Equal(t, "log", event)
+	assert.Contains(t, data, "poll failed")
+	event, data = next()
+	assert.Equal(t, "devices", event)
+	assert.Contains(t, data, "SEGGER J-Link")
+	event, data = next()
+	assert.Equal(t, "added", event)
+	assert.Contains(t, data, `"type":"added"`)
+}
+
+func TestListen_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usb-tree.sock")
+
+	listener, err := Listen("unix:" + path)
+	require.NoError(t, err)
+	_, err = Listen("unix:" + path)
+	assert.Error(t, err, "a socket in use must not be replaced")
+	require.NoError(t, listener.Close())
+
+	listener, err = Listen("unix:" + path)
+	require.NoError(t, err)
+	assert.NoError(t, listener.Close())
+}
+
+func TestServer_Health(t *testing.T) {
+	httpServer := httptest.NewServer(NewServer())
+	defer httpServer.Close()
+
+	var status HealthStatus
+	assert.Equal(t, http.StatusServiceUnavailable, getJSON(t, httpServer.URL+"/health", &status))
+
+	setHealthState(HealthOK)
+	defer setHealthState(HealthStopped)
+	require.Equal(t, http.StatusOK, getJSON(t, httpServer.URL+"/health", &status))
+	assert.Equal(t, HealthOK, status.State)
+}
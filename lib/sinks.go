@@ -0,0 +1,181 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultJournalSocket is where the systemd journal receives entries in its native protocol.
+const DefaultJournalSocket = "/run/systemd/journal/socket"
+
+// syslogIdentifier names usb-tree in the entries it writes to the system log.
+const syslogIdentifier = "usb-tree"
+
+// An EventSink writes Events to a system log.
+type EventSink interface {
+	Send(event Event) error
+	Close() error
+}
+
+// eventFields returns the structured fields describing an Event, so that for example
+// `journalctl USB_VID=0483` finds the Events of a vendor.
+func eventFields(event Event) [][2]string {
+	return [][2]string{
+		{"USB_EVENT", string(event.Type)},
+		{"USB_VID", event.Device.VendorID},
+		{"USB_PID", event.Device.ProductID},
+		{"USB_SERIAL", event.Device.Serial},
+		{"USB_PORT", event.Device.PortPath()},
+		{"USB_NAME", strings.TrimSpace(event.Device.DisplayName())},
+	}
+}
+
+// eventMessage returns the human readable line describing an Event.
+func eventMessage(event Event) string {
+	return fmt.Sprintf("USB device %s: %s on %s", event.Type, strings.TrimSpace(event.Device.DisplayName()), event.Device.PortPath())
+}
+
+// eventPriority returns the syslog severity of an Event: warning for policy violations, info otherwise.
+func eventPriority(event Event) int {
+	if event.Type == EventViolation {
+		return 4
+	}
+
+	return 6
+}
+
+// A JournalSink writes Events to the systemd journal with the native protocol, with the fields of the Event
+// as journal fields.
+type JournalSink struct {
+	conn *net.UnixConn
+}
+
+// NewJournalSink connects to the journal socket, usually DefaultJournalSocket.
+func NewJournalSink(socketPath string) (*JournalSink, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &JournalSink{conn: conn}, nil
+}
+
+// Send writes one journal entry for the Event.
+func (j *JournalSink) Send(event Event) error {
+	var entry bytes.Buffer
+	writeJournalField(&entry, "MESSAGE", eventMessage(event))
+	writeJournalField(&entry, "PRIORITY", fmt.Sprint(eventPriority(event)))
+	writeJournalField(&entry, "SYSLOG_IDENTIFIER", syslogIdentifier)
+	for _, field := range eventFields(event) {
+		writeJournalField(&entry, field[0], field[1])
+	}
+
+	_, err := j.conn.Write(entry.Bytes())
+
+	return err
+}
+
+// Close disconnects from the journal.
+func (j *JournalSink) Close() error {
+	return j.conn.Close()
+}
+
+// writeJournalField appends a field in the journal native protocol, which frames values containing newlines
+// with their length.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// A SyslogSink writes Events to syslog in the BSD format of RFC 3164, followed by the fields of the Event as
+// KEY=value pairs.
+type SyslogSink struct {
+	conn     net.Conn
+	hostname string
+}
+
+// NewSyslogSink connects to a syslog daemon at address: "unix:" followed by a socket path, usually
+// "unix:/dev/log", or "udp:" or "tcp:" followed by a host and port.
+func NewSyslogSink(address string) (*SyslogSink, error) {
+	network, target, ok := strings.Cut(address, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog address %q, expected unix:, udp: or tcp: followed by the address", address)
+	}
+
+	var conn net.Conn
+	var err error
+	switch network {
+	case "unix":
+		// Local syslog daemons listen on datagram sockets, some on stream sockets.
+		if conn, err = net.Dial("unixgram", target); err != nil {
+			conn, err = net.Dial("unix", target)
+		}
+	case "udp", "tcp":
+		conn, err = net.Dial(network, target)
+	default:
+		return nil, fmt.Errorf("invalid syslog address %q, expected unix:, udp: or tcp: followed by the address", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &SyslogSink{conn: conn, hostname: hostname}, nil
+}
+
+// Send writes one syslog message for the Event.
+func (s *SyslogSink) Send(event Event) error {
+	const facilityUser = 1
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "<%d>%s %s %s[%d]: %s", facilityUser*8+eventPriority(event), event.Time.Format(time.Stamp),
+		s.hostname, syslogIdentifier, os.Getpid(), eventMessage(event))
+	for _, field := range eventFields(event) {
+		fmt.Fprintf(&message, " %s=%q", field[0], field[1])
+	}
+	message.WriteByte('\n')
+
+	_, err := s.conn.Write([]byte(message.String()))
+
+	return err
+}
+
+// Close disconnects from syslog.
+func (s *SyslogSink) Close() error {
+	return s.conn.Close()
+}
+
+// ForwardEvents sends every published Event to the sinks until ctx is done. Failures are added to the log.
+func ForwardEvents(ctx context.Context, sinks ...EventSink) {
+	events, unsubscribe := Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case event := <-events:
+			for _, sink := range sinks {
+				if err := sink.Send(event); err != nil {
+					getLogger().Error("forwarding event failed", "type", event.Type, "device", event.Device.Key(), "error", err)
+					addErrorLog(fmt.Sprintf("Failed to forward %s event: %v", event.Type, err), time.Now(), StateError)
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package lib
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listenDatagrams stands in for the journal or a syslog daemon on a unix datagram socket.
+func listenDatagrams(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return path, conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 65536)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func TestJournalSink(t *testing.T) {
+	path, conn := listenDatagrams(t)
+	sink, err := NewJournalSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Send(Event{Time: time.Now(), Type: EventAdded, Device: bootloader}))
+	entry := readDatagram(t, conn)
+
+	assert.Contains(t, entry, "MESSAGE=USB device added: STM32 BOOTLOADER on 1-3\n")
+	assert.Contains(t, entry, "PRIORITY=6\n")
+	assert.Contains(t, entry, "SYSLOG_IDENTIFIER=usb-tree\n")
+	assert.Contains(t, entry, "USB_VID=0483\nUSB_PID=df11\nUSB_SERIAL=385B37673033\nUSB_PORT=1-3\n")
+	assert.Contains(t, entry, "USB_EVENT=added\n")
+}
+
+func TestWriteJournalField_Multiline(t *testing.T) {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", "two\nlines")
+
+	var expected bytes.Buffer
+	expected.WriteString("MESSAGE\n")
+	require.NoError(t, binary.Write(&expected, binary.LittleEndian, uint64(9)))
+	expected.WriteString("two\nlines\n")
+	assert.Equal(t, expected.Bytes(), buf.Bytes())
+}
+
+func TestSyslogSink(t *testing.T) {
+	path, conn := listenDatagrams(t)
+	sink, err := NewSyslogSink("unix:" + path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Send(Event{Time: time.Now(), Type: EventViolation, Device: bootloader}))
+	message := readDatagram(t, conn)
+
+	assert.True(t, strings.HasPrefix(message, "<12>"), "user facility with warning severity, got %q", message)
+	assert.Contains(t, message, " usb-tree[")
+	assert.Contains(t, message, `]: USB device violation: STM32 BOOTLOADER on 1-3 USB_EVENT="violation" USB_VID="0483"`)
+
+	_, err = NewSyslogSink("/dev/log")
+	assert.Error(t, err)
+}
+
+func TestForwardEvents(t *testing.T) {
+	path, conn := listenDatagrams(t)
+	sink, err := NewJournalSink(path)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fakeRefresh([]Device{device1})
+	go ForwardEvents(ctx, sink)
+	assert.Eventually(t, func() bool {
+		subscribersLock.Lock()
+		defer subscribersLock.Unlock()
+		return len(subscribers) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	deviceDiff([]Device{device1, device2}, time.Now())
+	assert.Contains(t, readDatagram(t, conn), "USB_VID=0002\n")
+}
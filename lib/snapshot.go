@@ -0,0 +1,53 @@
+package lib
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// A Snapshot records the Devices connected to a machine, and its log, at one point in time so they can be
+// exported or compared later.
+type Snapshot struct {
+	Time     time.Time `json:"time"`
+	Hostname string    `json:"hostname"`
+	Devices  []Device  `json:"devices"`
+	Log      []Log     `json:"log,omitempty"`
+}
+
+// NewSnapshot captures the Devices and the current log of this machine.
+func NewSnapshot(devices []Device) Snapshot {
+	hostname, _ := os.Hostname()
+
+	return Snapshot{
+		Time:     time.Now(),
+		Hostname: hostname,
+		Devices:  devices,
+		Log:      GetLog(),
+	}
+}
+
+// LoadSnapshot reads a Snapshot written by SaveSnapshot.
+func LoadSnapshot(path string) (Snapshot, error) {
+	var snapshot Snapshot
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot writes the Snapshot to path as JSON.
+func SaveSnapshot(path string, snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
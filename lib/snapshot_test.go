@@ -0,0 +1,24 @@
+package lib
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_SaveLoad(t *testing.T) {
+	logs = nil
+	path := filepath.Join(t.TempDir(), "lab.json")
+	snapshot := NewSnapshot([]Device{device4, probe})
+	assert.NotEmpty(t, snapshot.Hostname)
+
+	require.NoError(t, SaveSnapshot(path, snapshot))
+	loaded, err := LoadSnapshot(path)
+	require.NoError(t, err)
+
+	assert.True(t, snapshot.Time.Equal(loaded.Time))
+	assert.Equal(t, snapshot.Devices, loaded.Devices)
+	assert.Equal(t, "1-3.2", loaded.Devices[1].PortPath())
+}
@@ -0,0 +1,47 @@
+package lib
+
+import "time"
+
+// A Source supplies the Devices and the log shown by the UIs: the Devices connected to this machine, or
+// those of a usb-tree daemon reached with Connect.
+type Source interface {
+	// Start begins monitoring and calls onUpdate, like Init, whenever the Devices change. A nil slice
+	// signals that the Devices could not be read. An error means the Devices could not be read at first; the
+	// monitoring goes on regardless, and Health tells whether it recovers.
+	Start(onUpdate func([]Device)) error
+	// Stop ends the monitoring begun by Start.
+	Stop()
+	// Refresh asks for the Devices to be read again and returns the time of the request.
+	Refresh() time.Time
+	// Log returns the log of the Devices.
+	Log() []Log
+	// Health returns the state of the monitoring.
+	Health() HealthStatus
+}
+
+// Local is the Source of the Devices connected to this machine.
+var Local Source = localSource{}
+
+type localSource struct{}
+
+func (localSource) Start(onUpdate func([]Device)) error {
+	_, err := Init(onUpdate)
+
+	return err
+}
+
+func (localSource) Stop() {
+	Stop()
+}
+
+func (localSource) Refresh() time.Time {
+	return RequestRefresh()
+}
+
+func (localSource) Log() []Log {
+	return GetLog()
+}
+
+func (localSource) Health() HealthStatus {
+	return Health()
+}
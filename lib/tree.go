@@ -0,0 +1,145 @@
+package lib
+
+import "iter"
+
+// A Tree is a list of root TreeNodes, as built by BuildDeviceTree.
+type Tree []*TreeNode
+
+// A WalkFunc is called by Walk for each TreeNode with its ancestors, the root first. It returns false to skip
+// the children of the node. The ancestors slice is reused, and must be copied to be kept.
+type WalkFunc func(node *TreeNode, ancestors []*TreeNode) bool
+
+// Walk calls visit for the nodes of the Tree in depth-first order, parents before their children.
+func (t Tree) Walk(visit WalkFunc) {
+	var ancestors []*TreeNode
+	for _, root := range t {
+		root.walk(visit, &ancestors)
+	}
+}
+
+// Walk calls visit for the node and its descendants in depth-first order, with the ancestors below the node.
+func (n *TreeNode) Walk(visit WalkFunc) {
+	var ancestors []*TreeNode
+	n.walk(visit, &ancestors)
+}
+
+func (n *TreeNode) walk(visit WalkFunc, ancestors *[]*TreeNode) {
+	if !visit(n, *ancestors) {
+		return
+	}
+
+	*ancestors = append(*ancestors, n)
+	for _, child := range n.Children {
+		child.walk(visit, ancestors)
+	}
+	*ancestors = (*ancestors)[:len(*ancestors)-1]
+}
+
+// Subtree returns the node and its descendants in depth-first order.
+func (n *TreeNode) Subtree() iter.Seq[*TreeNode] {
+	return func(yield func(*TreeNode) bool) {
+		n.yieldAll(yield)
+	}
+}
+
+func (n *TreeNode) yieldAll(yield func(*TreeNode) bool) bool {
+	if !yield(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.yieldAll(yield) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Flatten returns the nodes of the Tree in the order they are displayed, with their index in that order. The
+// children of a node are included when expanded returns true for it, or always when expanded is nil.
+func (t Tree) Flatten(expanded func(*TreeNode) bool) iter.Seq2[int, *TreeNode] {
+	return func(yield func(int, *TreeNode) bool) {
+		index := 0
+		var flatten func(node *TreeNode) bool
+		flatten = func(node *TreeNode) bool {
+			if !yield(index, node) {
+				return false
+			}
+			index++
+			if expanded != nil && !expanded(node) {
+				return true
+			}
+			for _, child := range node.Children {
+				if !flatten(child) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for _, root := range t {
+			if !flatten(root) {
+				return
+			}
+		}
+	}
+}
+
+// FindByKey returns the node of the Device with the key, or nil if the Tree has none.
+func (t Tree) FindByKey(key string) *TreeNode {
+	node, _ := t.find(key)
+	return node
+}
+
+// Ancestors returns the ancestors of the node of the Device with the key, the root first. It returns nil for
+// roots and for keys the Tree does not have.
+func (t Tree) Ancestors(key string) []*TreeNode {
+	_, ancestors := t.find(key)
+	return ancestors
+}
+
+// Depth returns the number of ancestors of the node of the Device with the key, or -1 if the Tree has none.
+func (t Tree) Depth(key string) int {
+	node, ancestors := t.find(key)
+	if node == nil {
+		return -1
+	}
+
+	return len(ancestors)
+}
+
+// find returns the node of the Device with the key and a copy of its ancestors.
+func (t Tree) find(key string) (found *TreeNode, foundAncestors []*TreeNode) {
+	t.Walk(func(node *TreeNode, ancestors []*TreeNode) bool {
+		if found != nil {
+			return false
+		}
+		if node.Key() == key {
+			found = node
+			if len(ancestors) > 0 {
+				foundAncestors = append([]*TreeNode(nil), ancestors...)
+			}
+		}
+		return found == nil
+	})
+
+	return found, foundAncestors
+}
+
+// Filter returns a copy of the Tree with the nodes keep returns true for and their ancestors. The Tree is not
+// modified.
+func (t Tree) Filter(keep func(*TreeNode) bool) Tree {
+	var kept Tree
+	for _, node := range t {
+		children := Tree(node.Children).Filter(keep)
+		if len(children) == 0 && !keep(node) {
+			continue
+		}
+		if children == nil {
+			children = Tree{}
+		}
+		kept = append(kept, &TreeNode{Device: node.Device, Children: children})
+	}
+
+	return kept
+}
@@ -0,0 +1,91 @@
+package lib
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var root2 = Device{Path: []int{}, Name: "Root 2", VendorID: "0007", ProductID: "0070", Speed: "Super", Bus: 2, State: StateNormal}
+
+// testTree returns Root with Child, Grandchild below it and Device 2, and Root 2 on another bus.
+func testTree() Tree {
+	return BuildDeviceTree([]Device{device4, device5, device6, device2, root2})
+}
+
+func nodeNames(nodes []*TreeNode) []string {
+	var names []string
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	return names
+}
+
+func TestTree_Walk(t *testing.T) {
+	var visited []string
+	var depths []int
+	testTree().Walk(func(node *TreeNode, ancestors []*TreeNode) bool {
+		visited = append(visited, node.Name)
+		depths = append(depths, len(ancestors))
+		return node.Name != "Child"
+	})
+
+	assert.Equal(t, []string{"Root", "Child", "Device 2", "Root 2"}, visited, "the children of Child are pruned")
+	assert.Equal(t, []int{0, 1, 1, 0}, depths)
+}
+
+func TestTreeNode_Subtree(t *testing.T) {
+	child := testTree()[0].Children[0]
+	assert.Equal(t, []string{"Child", "Grandchild"}, nodeNames(slices.Collect(child.Subtree())))
+
+	var first []*TreeNode
+	for node := range testTree()[0].Subtree() {
+		first = append(first, node)
+		break
+	}
+	assert.Equal(t, []string{"Root"}, nodeNames(first))
+}
+
+func TestTree_Flatten(t *testing.T) {
+	var names []string
+	var indexes []int
+	for i, node := range testTree().Flatten(nil) {
+		names = append(names, node.Name)
+		indexes = append(indexes, i)
+	}
+	assert.Equal(t, []string{"Root", "Child", "Grandchild", "Device 2", "Root 2"}, names)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, indexes)
+
+	names = nil
+	for _, node := range testTree().Flatten(func(node *TreeNode) bool { return node.Name != "Root" }) {
+		names = append(names, node.Name)
+	}
+	assert.Equal(t, []string{"Root", "Root 2"}, names, "collapsed nodes hide their children")
+}
+
+func TestTree_FindByKey(t *testing.T) {
+	tree := testTree()
+	assert.Equal(t, "Grandchild", tree.FindByKey(device6.Key()).Name)
+	assert.Nil(t, tree.FindByKey(device1.Key()))
+
+	assert.Equal(t, []string{"Root", "Child"}, nodeNames(tree.Ancestors(device6.Key())))
+	assert.Empty(t, tree.Ancestors(root2.Key()))
+
+	assert.Equal(t, 2, tree.Depth(device6.Key()))
+	assert.Equal(t, 0, tree.Depth(root2.Key()))
+	assert.Equal(t, -1, tree.Depth(device1.Key()))
+}
+
+func TestTree_Filter(t *testing.T) {
+	tree := testTree()
+	filtered := tree.Filter(func(node *TreeNode) bool { return node.Name == "Grandchild" })
+
+	assert.Equal(t, []string{"Root", "Child", "Grandchild"}, nodeNames(slices.Collect(filtered[0].Subtree())))
+	assert.Len(t, filtered, 1)
+	assert.NotNil(t, filtered[0].Children[0].Children[0].Children)
+	assert.Len(t, tree[0].Children, 2, "the tree is not modified")
+
+	assert.Empty(t, tree.Filter(func(*TreeNode) bool { return false }))
+}
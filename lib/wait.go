@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// waitPollInterval is how often WaitFor enumerates the Devices.
+const waitPollInterval = 250 * time.Millisecond
+
+// WaitFor blocks until a Device selected by the matcher is connected, when state is StateAdded, or until none
+// is, when state is StateRemoved. It returns at once if that is already the case. The Device returned is the
+// one found or the one last seen before its removal. WaitFor enumerates the Devices itself, so it works
+// whether or not Init was called.
+//
+// WaitFor returns the PollError of a failed enumeration at once when retrying cannot help, i.e. for
+// ErrPermissionDenied and ErrLibusbUnavailable, and retries the others. When the context ends first, it
+// returns the error of the last enumeration if that failed, otherwise the context's error.
+func WaitFor(ctx context.Context, matcher DeviceMatcher, state LogState) (Device, error) {
+	if matcher.IsZero() {
+		return Device{}, errors.New("wait: matcher is empty")
+	}
+	if state != StateAdded && state != StateRemoved {
+		return Device{}, fmt.Errorf("wait: unsupported state %q", state)
+	}
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	var lastSeen Device
+	for {
+		_, devices, err := pollDevices()
+		switch {
+		case errors.Is(err, ErrPermissionDenied), errors.Is(err, ErrLibusbUnavailable):
+			return Device{}, err
+		case err == nil:
+			found, ok := findDevice(devices, matcher)
+			if ok {
+				lastSeen = found
+			}
+			if ok == (state == StateAdded) {
+				return lastSeen, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return Device{}, err
+			}
+			return Device{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// findDevice returns the first Device selected by the matcher.
+func findDevice(devices []Device, matcher DeviceMatcher) (Device, bool) {
+	for _, device := range devices {
+		if matcher.Matches(device) {
+			return device, true
+		}
+	}
+
+	return Device{}, false
+}
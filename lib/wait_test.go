@@ -0,0 +1,88 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePolls replaces the enumeration used by WaitFor with the given snapshots, repeating the last one.
+func fakePolls(t *testing.T, snapshots ...[]Device) {
+	var lock sync.Mutex
+	pollDevices = func() (time.Time, []Device, error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		devices := snapshots[0]
+		if len(snapshots) > 1 {
+			snapshots = snapshots[1:]
+		}
+		return time.Now(), devices, nil
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+}
+
+func TestWaitFor_Added(t *testing.T) {
+	fakePolls(t, []Device{device1}, []Device{device1}, []Device{device1, bootloader})
+
+	device, err := WaitFor(context.Background(), DeviceMatcher{VendorID: "0483", ProductID: "DF11"}, StateAdded)
+	require.NoError(t, err)
+	assert.Equal(t, "/dev/bus/usb/001/007", device.DevNode)
+}
+
+func TestWaitFor_AlreadyConnected(t *testing.T) {
+	fakePolls(t, []Device{bootloader})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := WaitFor(ctx, DeviceMatcher{Serial: bootloader.Serial}, StateAdded)
+	assert.NoError(t, err)
+}
+
+func TestWaitFor_Removed(t *testing.T) {
+	fakePolls(t, []Device{device1, bootloader}, []Device{device1})
+
+	device, err := WaitFor(context.Background(), DeviceMatcher{VendorID: "0483"}, StateRemoved)
+	require.NoError(t, err)
+	assert.Equal(t, bootloader.Serial, device.Serial, "the device last seen is returned")
+}
+
+func TestWaitFor_Timeout(t *testing.T) {
+	fakePolls(t, []Device{device1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := WaitFor(ctx, DeviceMatcher{VendorID: "0483"}, StateAdded)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitFor_InvalidArguments(t *testing.T) {
+	_, err := WaitFor(context.Background(), DeviceMatcher{}, StateAdded)
+	assert.Error(t, err)
+	_, err = WaitFor(context.Background(), DeviceMatcher{VendorID: "0483"}, StateNormal)
+	assert.Error(t, err)
+}
+
+func TestWaitFor_PollErrors(t *testing.T) {
+	var err error
+	pollDevices = func() (time.Time, []Device, error) {
+		return time.Now(), nil, err
+	}
+	t.Cleanup(func() { pollDevices = getDevices })
+
+	err = &PollError{Kind: ErrLibusbUnavailable, Err: errors.New("libusb_init failed")}
+	_, waitErr := WaitFor(context.Background(), DeviceMatcher{VendorID: "0483"}, StateAdded)
+	assert.ErrorIs(t, waitErr, ErrLibusbUnavailable, "should not retry when libusb is missing")
+
+	err = &PollError{Kind: ErrUdevFailure, Err: errors.New("udev is busy")}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, waitErr = WaitFor(ctx, DeviceMatcher{VendorID: "0483"}, StateAdded)
+	assert.ErrorIs(t, waitErr, ErrUdevFailure, "the last failure should be returned instead of the timeout")
+	assert.NotErrorIs(t, waitErr, context.DeadlineExceeded)
+}
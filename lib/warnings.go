@@ -0,0 +1,132 @@
+package lib
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A WarningKind describes a problem with how Devices are connected.
+type WarningKind string
+
+// These constants represent the kinds of Warning.
+const (
+	WarningSpeed WarningKind = "speed"
+	WarningPower WarningKind = "power"
+)
+
+// A Warning points out a Device running below its capabilities or drawing more power than its port supplies.
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Device  Device      `json:"device"`
+	Message string      `json:"message"`
+}
+
+// Port power budgets in mA, from the USB 2.0 and 3.x specifications.
+const (
+	portPower            = 500 // a port of a root or self-powered hub
+	superSpeedPortPower  = 900
+	busPoweredPortPower  = 100 // a port of a bus-powered hub
+	superSpeedBusPowered = 150
+)
+
+// Warnings checks the device tree for speed bottlenecks and exceeded power budgets.
+func Warnings(roots []*TreeNode) []Warning {
+	var warnings []Warning
+
+	Tree(roots).Walk(func(node *TreeNode, ancestors []*TreeNode) bool {
+		var parent *TreeNode
+		if len(ancestors) > 0 {
+			parent = ancestors[len(ancestors)-1]
+		}
+		warnings = append(warnings, speedWarnings(node, parent)...)
+		warnings = append(warnings, powerWarnings(node, parent)...)
+		return true
+	})
+
+	return warnings
+}
+
+func speedWarnings(node *TreeNode, parent *TreeNode) []Warning {
+	if parent == nil {
+		return nil
+	}
+
+	speed := speedMbps(node.Speed)
+	version := usbVersion(node.USBVersion)
+	if speed == 0 {
+		return nil
+	}
+
+	if version >= 3 && speed <= 480 {
+		return []Warning{{
+			Kind:   WarningSpeed,
+			Device: node.Device,
+			Message: fmt.Sprintf("%s supports USB %s but runs at %s Mbps; a USB 2 port, hub or cable limits it",
+				node.DisplayName(), node.USBVersion, formatMbps(speed)),
+		}}
+	}
+	// USB 2 hubs run at high speed, but many full-speed keyboards and mice declare USB 2.00 as well, so only
+	// hubs are known to be slowed down by a full-speed hub.
+	if len(parent.Path) > 0 && version >= 2 && node.Class == "hub" && speedMbps(parent.Speed) == 12 {
+		return []Warning{{
+			Kind:   WarningSpeed,
+			Device: node.Device,
+			Message: fmt.Sprintf("%s supports USB %s but is limited to 12 Mbps by the full-speed hub %s",
+				node.DisplayName(), node.USBVersion, parent.DisplayName()),
+		}}
+	}
+
+	return nil
+}
+
+func powerWarnings(node *TreeNode, parent *TreeNode) []Warning {
+	var warnings []Warning
+
+	if parent != nil && len(parent.Path) > 0 && !parent.SelfPowered && !node.SelfPowered {
+		budget := busPoweredPortPower
+		if speedMbps(node.Speed) >= 5000 {
+			budget = superSpeedBusPowered
+		}
+		if node.MaxPower > budget {
+			warnings = append(warnings, Warning{
+				Kind:   WarningPower,
+				Device: node.Device,
+				Message: fmt.Sprintf("%s draws up to %d mA but the bus-powered hub %s supplies %d mA per port",
+					node.DisplayName(), node.MaxPower, parent.DisplayName(), budget),
+			})
+		}
+	}
+
+	if len(node.Path) > 0 && len(node.Children) > 0 && !node.SelfPowered {
+		total := node.MaxPower
+		for _, child := range node.Children {
+			if !child.SelfPowered {
+				total += child.MaxPower
+			}
+		}
+		budget := portPower
+		if speedMbps(node.Speed) >= 5000 {
+			budget = superSpeedPortPower
+		}
+		if total > budget {
+			warnings = append(warnings, Warning{
+				Kind:   WarningPower,
+				Device: node.Device,
+				Message: fmt.Sprintf("bus-powered hub %s and its devices draw up to %d mA, more than the %d mA of its port",
+					node.DisplayName(), total, budget),
+			})
+		}
+	}
+
+	return warnings
+}
+
+// usbVersion parses a USB specification release such as "3.20"; it returns 0 when unknown.
+func usbVersion(version string) float64 {
+	parsed, err := strconv.ParseFloat(version, 64)
+	if err != nil {
+		return 0
+	}
+
+	return parsed
+}
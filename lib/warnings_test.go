@@ -0,0 +1,65 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func warningKinds(warnings []Warning) []WarningKind {
+	var result []WarningKind
+	for _, warning := range warnings {
+		result = append(result, warning.Kind)
+	}
+
+	return result
+}
+
+func TestWarnings_SuperSpeedDeviceOnUSB2(t *testing.T) {
+	root := Device{Bus: 1, Name: "Root hub", Speed: "480", USBVersion: "2.00", SelfPowered: true}
+	ssd := Device{Bus: 1, Path: []int{2}, Name: "SSD", Speed: "480", USBVersion: "3.20", MaxPower: 400}
+
+	warnings := Warnings(BuildDeviceTree([]Device{root, ssd}))
+	require.Equal(t, []WarningKind{WarningSpeed}, warningKinds(warnings))
+	assert.Equal(t, "SSD supports USB 3.20 but runs at 480 Mbps; a USB 2 port, hub or cable limits it", warnings[0].Message)
+
+	ssd.Speed = "5000"
+	assert.Empty(t, Warnings(BuildDeviceTree([]Device{root, ssd})))
+}
+
+func TestWarnings_FullSpeedHub(t *testing.T) {
+	root := Device{Bus: 1, Name: "Root hub", Speed: "480", USBVersion: "2.00", SelfPowered: true}
+	hub := Device{Bus: 1, Path: []int{1}, Name: "Old hub", Speed: "12", USBVersion: "1.10", SelfPowered: true}
+	usb2Hub := Device{Bus: 1, Path: []int{1, 1}, Name: "USB 2 hub", Speed: "12", USBVersion: "2.00", Class: "hub", MaxPower: 100}
+
+	warnings := Warnings(BuildDeviceTree([]Device{root, hub, usb2Hub}))
+	require.Equal(t, []WarningKind{WarningSpeed}, warningKinds(warnings))
+	assert.Contains(t, warnings[0].Message, "limited to 12 Mbps by the full-speed hub Old hub")
+}
+
+func TestWarnings_FullSpeedDeviceDeclaringUSB2(t *testing.T) {
+	root := Device{Bus: 1, Name: "Root hub", Speed: "480", USBVersion: "2.00", SelfPowered: true}
+	hub := Device{Bus: 1, Path: []int{1}, Name: "Old hub", Speed: "12", USBVersion: "1.10", SelfPowered: true}
+	keyboard := Device{Bus: 1, Path: []int{1, 1}, Name: "Keyboard", Speed: "12", USBVersion: "2.00", Class: "per-interface", MaxPower: 100}
+
+	assert.Empty(t, Warnings(BuildDeviceTree([]Device{root, hub, keyboard})),
+		"a full-speed device declaring USB 2.00 is not known to be slowed down")
+}
+
+func TestWarnings_PowerBudget(t *testing.T) {
+	root := Device{Bus: 1, Name: "Root hub", Speed: "480", USBVersion: "2.00", SelfPowered: true}
+	hub := Device{Bus: 1, Path: []int{1}, Name: "Travel hub", Speed: "480", USBVersion: "2.00", MaxPower: 100}
+	drive := Device{Bus: 1, Path: []int{1, 1}, Name: "Drive", Speed: "480", USBVersion: "2.00", MaxPower: 500}
+	mouse := Device{Bus: 1, Path: []int{1, 2}, Name: "Mouse", Speed: "1.5", USBVersion: "2.00", MaxPower: 98}
+
+	warnings := Warnings(BuildDeviceTree([]Device{root, hub, drive, mouse}))
+	assert.Equal(t, []WarningKind{WarningPower, WarningPower}, warningKinds(warnings))
+	assert.Equal(t, "bus-powered hub Travel hub and its devices draw up to 698 mA, more than the 500 mA of its port",
+		warnings[0].Message)
+	assert.Equal(t, "Drive draws up to 500 mA but the bus-powered hub Travel hub supplies 100 mA per port",
+		warnings[1].Message)
+
+	hub.SelfPowered = true
+	assert.Empty(t, Warnings(BuildDeviceTree([]Device{root, hub, drive, mouse})))
+}